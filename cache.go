@@ -13,9 +13,27 @@ import (
 type Item[T any] struct {
 	Object     T
 	Expiration int64
-}
-
-// Expired Returns true if the item has expired.
+	// Sliding is the duration by which Expiration is pushed forward each
+	// time the item is read, or zero if the item has a fixed expiration
+	// time. See SetSliding.
+	Sliding time.Duration
+	// Cost is the item's share of the budget of a cache bounded by
+	// WithMaxCost. It is zero in other caches.
+	Cost int64
+	// Version changes whenever the item's value is stored or changed, and
+	// is never reused within a cache. See GetWithVersion.
+	Version uint64
+	// Tags are the tags given to SetWithTags, by which the item can be
+	// deleted using InvalidateTag.
+	Tags []string
+	// added is the Version of the item that added the key to the map. Items
+	// replacing it keep it, so Range can tell keys that were added while
+	// it runs from keys whose value changed.
+	added uint64
+}
+
+// Expired Returns true if the item has expired. It always uses the system time,
+// even for items of a cache with a different Clock.
 func (item *Item[T]) Expired() bool {
 	if item.Expiration == 0 {
 		return false
@@ -39,149 +57,335 @@ const (
 	DefaultExpiration time.Duration = 0
 )
 
+// Cache is a cache with string keys. It is the most common kind of cache; see
+// KeyedCache for caches keyed by other types.
 type Cache[T any] struct {
-	*cache[T]
+	*cache[string, T]
+	// If this is confusing, see the comment at the bottom of New()
+}
+
+// KeyedCache is a cache with keys of any comparable type, e.g. integer IDs or
+// structs, which saves formatting them into strings on every access. Apart
+// from the key type it behaves exactly like Cache.
+type KeyedCache[K comparable, T any] struct {
+	*cache[K, T]
 	// If this is confusing, see the comment at the bottom of New()
 }
 
-type cache[T any] struct {
+type cache[K comparable, T any] struct {
 	defaultExpiration time.Duration
-	items             map[string]*Item[T]
+	items             map[K]*Item[T]
 	mu                sync.RWMutex
-	onEvicted         func(string, T)
-	janitor           *janitor[T]
+	onEvicted         func(K, T)
+	onEvictedReason   func(K, Item[T], EvictionReason)
+	janitor           *janitor
+	maxItems          int
+	maxCost           int64
+	cost              int64
+	costFunc          func(T) int64
+	policy            policy[K]
+	index             keyIndex[K]
+	tags              map[string]map[K]struct{}
+	loads             loadGroup[K, T]
+	refresh           *RefreshPolicy[K, T]
+	expirations       expirationIndex[K]
+	sliding           bool
+	clock             Clock
+	closed            bool
+	stopContext       func() bool
+	stats             stats
+	name              string
+	version           uint64
+	flushes           uint64
+}
+
+// now returns the current time of the cache's clock in Unix nanoseconds.
+func (c *cache[K, T]) now() int64 {
+	return c.clock.Now().UnixNano()
+}
+
+// expired reports whether item has expired according to the cache's clock.
+func (c *cache[K, T]) expired(item *Item[T]) bool {
+	if item.Expiration == 0 {
+		return false
+	}
+	return c.now() > item.Expiration
 }
 
 // Set Add an item to the cache, replacing any existing item. If the duration is 0
 // (DefaultExpiration), the cache's default expiration time is used. If it is -1
 // (NoExpiration), the item never expires.
-func (c *cache[T]) Set(k string, x T, d time.Duration) {
+func (c *cache[K, T]) Set(k K, x T, d time.Duration) {
 	// "Inlining" of set
 	var e int64
+	var sd time.Duration
 	if d == DefaultExpiration {
 		d = c.defaultExpiration
 	}
 	if d > 0 {
-		e = time.Now().Add(d).UnixNano()
+		e = c.clock.Now().Add(d).UnixNano()
+		if c.sliding {
+			sd = d
+		}
 	}
 	c.mu.Lock()
-	c.items[k] = &Item[T]{
+	evictedItems := c.insert(k, &Item[T]{
 		Object:     x,
 		Expiration: e,
-	}
+		Sliding:    sd,
+	})
 	// TODO: Calls to mu.Unlock are currently not deferred because defer
 	// adds ~200 ns (as of go1.)
 	c.mu.Unlock()
+	c.evicted(evictedItems)
 }
 
-func (c *cache[T]) set(k string, x T, d time.Duration) {
+func (c *cache[K, T]) set(k K, x T, d time.Duration) []evictedItem[K, T] {
 	var e int64
+	var sd time.Duration
 	if d == DefaultExpiration {
 		d = c.defaultExpiration
 	}
 	if d > 0 {
-		e = time.Now().Add(d).UnixNano()
+		e = c.clock.Now().Add(d).UnixNano()
+		if c.sliding {
+			sd = d
+		}
 	}
-	c.items[k] = &Item[T]{
+	return c.insert(k, &Item[T]{
 		Object:     x,
 		Expiration: e,
+		Sliding:    sd,
+	})
+}
+
+// insert stores item under k. If the cache is bounded, k becomes the most
+// recently used key, and the least recently used items are deleted until the
+// cache is back within its limits. The overwritten and deleted items are
+// returned if there is an eviction function. Nothing is stored if the cache
+// is closed.
+func (c *cache[K, T]) insert(k K, item *Item[T]) []evictedItem[K, T] {
+	if c.closed {
+		return nil
+	}
+	var evictedItems []evictedItem[K, T]
+	if c.maxCost > 0 && item.Cost < 1 {
+		item.Cost = c.costOf(item.Object)
+	}
+	n := len(c.items)
+	if c.onEvictedReason != nil || c.maxCost > 0 {
+		if ov, found := c.items[k]; found {
+			c.cost -= ov.Cost
+			if c.onEvictedReason != nil {
+				evictedItems = append(evictedItems, evictedItem[K, T]{k, ov, Replaced})
+			}
+		}
+	}
+	item.Version = c.nextVersion()
+	item.added = item.Version
+	if ov, found := c.items[k]; found {
+		item.added = ov.added
+		c.untag(k, ov)
+	}
+	c.items[k] = item
+	c.tag(k, item)
+	if len(c.items) == n {
+		c.stats.replacements.Add(1)
+	}
+	c.cost += item.Cost
+	c.expirations.set(k, item.Expiration)
+	if c.index != nil {
+		c.index.add(k)
+	}
+	c.stats.sets.Add(1)
+	if c.policy == nil {
+		return evictedItems
+	}
+	c.policy.add(k)
+	if c.maxCost > 0 && item.Cost > c.maxCost {
+		// The item can never fit, so don't evict everything else for it.
+		c.policy.remove(k)
+		evictedItems = c.evict(k, evictedItems)
+	}
+	for c.overCapacity() {
+		ek, ok := c.policy.evict()
+		if !ok {
+			break
+		}
+		evictedItems = c.evict(ek, evictedItems)
 	}
+	return evictedItems
+}
+
+// overCapacity reports whether a bounded cache holds more items, or more
+// cost, than its limits allow.
+func (c *cache[K, T]) overCapacity() bool {
+	return (c.maxItems > 0 && len(c.items) > c.maxItems) ||
+		(c.maxCost > 0 && c.cost > c.maxCost)
+}
+
+// evict deletes k to keep the cache within its limits, and appends the item
+// to evictedItems if there is an eviction function.
+func (c *cache[K, T]) evict(k K, evictedItems []evictedItem[K, T]) []evictedItem[K, T] {
+	ov, found := c.delete(k)
+	if found {
+		c.stats.evictions.Add(1)
+		if c.observesEvictions() {
+			evictedItems = append(evictedItems, evictedItem[K, T]{k, ov, CapacityEvicted})
+		}
+	}
+	return evictedItems
 }
 
 // SetDefault Add an item to the cache, replacing any existing item, using the default
 // expiration.
-func (c *cache[T]) SetDefault(k string, x T) {
+func (c *cache[K, T]) SetDefault(k K, x T) {
 	c.Set(k, x, DefaultExpiration)
 }
 
 // Add an item to the cache only if an item doesn't already exist for the given
 // key, or if the existing item has expired. Returns an error otherwise.
-func (c *cache[T]) Add(k string, x T, d time.Duration) error {
+func (c *cache[K, T]) Add(k K, x T, d time.Duration) error {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
 	_, found := c.get(k)
 	if found {
 		c.mu.Unlock()
-		return fmt.Errorf("item %s already exists", k)
+		return fmt.Errorf("item %v already exists", k)
 	}
-	c.set(k, x, d)
+	evictedItems := c.set(k, x, d)
 	c.mu.Unlock()
+	c.evicted(evictedItems)
 	return nil
 }
 
 // Replace Set a new value for the cache key only if it already exists, and the existing
 // item hasn't expired. Returns an error otherwise.
-func (c *cache[T]) Replace(k string, x T, d time.Duration) error {
+func (c *cache[K, T]) Replace(k K, x T, d time.Duration) error {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
 	_, found := c.get(k)
 	if !found {
 		c.mu.Unlock()
-		return fmt.Errorf("item %s doesn't exist", k)
+		return fmt.Errorf("item %v doesn't exist", k)
 	}
-	c.set(k, x, d)
+	evictedItems := c.set(k, x, d)
 	c.mu.Unlock()
+	c.evicted(evictedItems)
 	return nil
 }
 
 // Get an item from the cache. Returns the item or nil, and a bool indicating
-// whether the key was found.
-func (c *cache[T]) Get(k string) (T, bool) {
+// whether the key was found. If the cache is bounded, the item becomes the most
+// recently used one. If the item has a sliding expiration, its expiration time
+// is pushed forward. If the cache has a RefreshPolicy, Get may return an item
+// that has expired and refresh it in the background.
+func (c *cache[K, T]) Get(k K) (T, bool) {
 	c.mu.RLock()
 	// "Inlining" of get and Expired
 	item, found := c.items[k]
 	if !found {
 		c.mu.RUnlock()
+		c.stats.misses.Add(1)
 		var zero T
 		return zero, false
 	}
+	var rp *RefreshPolicy[K, T]
 	if item.Expiration > 0 {
-		if time.Now().UnixNano() > item.Expiration {
+		now := c.now()
+		if now > item.Expiration && !c.refresh.serveStale(item, now) {
 			c.mu.RUnlock()
+			c.stats.misses.Add(1)
 			var t T
 			return t, false
 		}
+		if c.refresh.due(item, now) {
+			rp = c.refresh
+		}
+	}
+	if c.policy != nil {
+		c.policy.access(k)
 	}
+	sliding := item.Sliding > 0
 	c.mu.RUnlock()
+	c.stats.hits.Add(1)
+	if rp != nil {
+		c.startRefresh(k, rp)
+	}
+	if sliding {
+		c.slide(k, item)
+	}
 	return item.Object, true
 }
 
 // GetWithExpiration returns an item and its expiration time from the cache.
 // It returns the item or nil, the expiration time if one is set (if the item
 // never expires a zero value for time.Time is returned), and a bool indicating
-// whether the key was found.
-func (c *cache[T]) GetWithExpiration(k string) (interface{}, time.Time, bool) {
+// whether the key was found. Like Get, it renews sliding expirations and may
+// return an expired item that is being refreshed.
+func (c *cache[K, T]) GetWithExpiration(k K) (interface{}, time.Time, bool) {
 	c.mu.RLock()
 	// "Inlining" of get and Expired
 	item, found := c.items[k]
 	if !found {
 		c.mu.RUnlock()
+		c.stats.misses.Add(1)
 		return nil, time.Time{}, false
 	}
 
 	if item.Expiration > 0 {
-		if time.Now().UnixNano() > item.Expiration {
+		now := c.now()
+		if now > item.Expiration && !c.refresh.serveStale(item, now) {
 			c.mu.RUnlock()
+			c.stats.misses.Add(1)
 			return nil, time.Time{}, false
 		}
+		var rp *RefreshPolicy[K, T]
+		if c.refresh.due(item, now) {
+			rp = c.refresh
+		}
 
 		// Return the item and the expiration time
+		if c.policy != nil {
+			c.policy.access(k)
+		}
+		e := item.Expiration
+		sliding := item.Sliding > 0
 		c.mu.RUnlock()
-		return item.Object, time.Unix(0, item.Expiration), true
+		c.stats.hits.Add(1)
+		if rp != nil {
+			c.startRefresh(k, rp)
+		}
+		if sliding {
+			e = c.slide(k, item)
+		}
+		return item.Object, time.Unix(0, e), true
 	}
 
 	// If expiration <= 0 (i.e. no expiration time set) then return the item
 	// and a zeroed time.Time
+	if c.policy != nil {
+		c.policy.access(k)
+	}
 	c.mu.RUnlock()
+	c.stats.hits.Add(1)
 	return item.Object, time.Time{}, true
 }
 
-func (c *cache[T]) get(k string) (interface{}, bool) {
+func (c *cache[K, T]) get(k K) (interface{}, bool) {
 	item, found := c.items[k]
 	if !found {
 		return nil, false
 	}
 	// "Inlining" of Expired
 	if item.Expiration > 0 {
-		if time.Now().UnixNano() > item.Expiration {
+		if c.now() > item.Expiration {
 			return nil, false
 		}
 	}
@@ -192,13 +396,22 @@ func (c *cache[T]) get(k string) (interface{}, bool) {
 // uint8, uint32, or uint64, float32 or float64 by n. Returns an error if the
 // item's value is not an integer, if it was not found, or if it is not
 // possible to increment it by n. To retrieve the incremented value, use one
-// of the specialized methods, e.g. IncrementInt64.
-func (c *cache[T]) Increment(k string, n int64) error {
+// of the specialized methods, e.g. IncrementInt64. Integers wrap around on
+// overflow; see IncrementWithMode for other ways of handling it.
+//
+// For a Cache of a numeric type, the Add function is preferred over Increment
+// and the typed methods such as IncrementInt64: it returns the result and
+// reports overflow instead of wrapping around.
+func (c *cache[K, T]) Increment(k K, n int64) error {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return fmt.Errorf("item %s not found", k)
+		return fmt.Errorf("item %v not found", k)
 	}
 	switch value := any(v.Object).(type) {
 	case int:
@@ -229,8 +442,9 @@ func (c *cache[T]) Increment(k string, n int64) error {
 		v.SetValue(value + float64(n))
 	default:
 		c.mu.Unlock()
-		return fmt.Errorf("the value for %s is not an integer", k)
+		return fmt.Errorf("the value for %v is not an integer", k)
 	}
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nil
@@ -241,12 +455,16 @@ func (c *cache[T]) Increment(k string, n int64) error {
 // possible to increment it by n. Pass a negative number to decrement the
 // value. To retrieve the incremented value, use one of the specialized methods,
 // e.g. IncrementFloat64.
-func (c *cache[T]) IncrementFloat(k string, n float64) error {
+func (c *cache[K, T]) IncrementFloat(k K, n float64) error {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return fmt.Errorf("item %s not found", k)
+		return fmt.Errorf("item %v not found", k)
 	}
 	switch value := any(v.Object).(type) {
 	case float32:
@@ -255,8 +473,9 @@ func (c *cache[T]) IncrementFloat(k string, n float64) error {
 		v.SetValue(value + n)
 	default:
 		c.mu.Unlock()
-		return fmt.Errorf("the value for %s does not have type float32 or float64", k)
+		return fmt.Errorf("the value for %v does not have type float32 or float64", k)
 	}
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nil
@@ -265,20 +484,25 @@ func (c *cache[T]) IncrementFloat(k string, n float64) error {
 // IncrementInt increment an item of type int by n. Returns an error if the item's value is
 // not an int, or if it was not found. If there is no error, the incremented
 // value is returned.
-func (c *cache[T]) IncrementInt(k string, n int) (int, error) {
+func (c *cache[K, T]) IncrementInt(k K, n int) (int, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(int)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an int", k)
+		return 0, fmt.Errorf("the value for %v is not an int", k)
 	}
 	nv := rv + n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -287,20 +511,25 @@ func (c *cache[T]) IncrementInt(k string, n int) (int, error) {
 // IncrementInt8 increment an item of type int8 by n. Returns an error if the item's value is
 // not an int8, or if it was not found. If there is no error, the incremented
 // value is returned.
-func (c *cache[T]) IncrementInt8(k string, n int8) (int8, error) {
+func (c *cache[K, T]) IncrementInt8(k K, n int8) (int8, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(int8)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an int8", k)
+		return 0, fmt.Errorf("the value for %v is not an int8", k)
 	}
 	nv := rv + n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -309,20 +538,25 @@ func (c *cache[T]) IncrementInt8(k string, n int8) (int8, error) {
 // IncrementInt16 increment an item of type int16 by n. Returns an error if the item's value is
 // not an int16, or if it was not found. If there is no error, the incremented
 // value is returned.
-func (c *cache[T]) IncrementInt16(k string, n int16) (int16, error) {
+func (c *cache[K, T]) IncrementInt16(k K, n int16) (int16, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(int16)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an int16", k)
+		return 0, fmt.Errorf("the value for %v is not an int16", k)
 	}
 	nv := rv + n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -331,20 +565,25 @@ func (c *cache[T]) IncrementInt16(k string, n int16) (int16, error) {
 // IncrementInt32 increment an item of type int32 by n. Returns an error if the item's value is
 // not an int32, or if it was not found. If there is no error, the incremented
 // value is returned.
-func (c *cache[T]) IncrementInt32(k string, n int32) (int32, error) {
+func (c *cache[K, T]) IncrementInt32(k K, n int32) (int32, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(int32)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an int32", k)
+		return 0, fmt.Errorf("the value for %v is not an int32", k)
 	}
 	nv := rv + n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -353,20 +592,25 @@ func (c *cache[T]) IncrementInt32(k string, n int32) (int32, error) {
 // IncrementInt64 increment an item of type int64 by n. Returns an error if the item's value is
 // not an int64, or if it was not found. If there is no error, the incremented
 // value is returned.
-func (c *cache[T]) IncrementInt64(k string, n int64) (int64, error) {
+func (c *cache[K, T]) IncrementInt64(k K, n int64) (int64, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(int64)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an int64", k)
+		return 0, fmt.Errorf("the value for %v is not an int64", k)
 	}
 	nv := rv + n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -375,20 +619,25 @@ func (c *cache[T]) IncrementInt64(k string, n int64) (int64, error) {
 // IncrementUint increment an item of type uint by n. Returns an error if the item's value is
 // not an uint, or if it was not found. If there is no error, the incremented
 // value is returned.
-func (c *cache[T]) IncrementUint(k string, n uint) (uint, error) {
+func (c *cache[K, T]) IncrementUint(k K, n uint) (uint, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(uint)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an uint", k)
+		return 0, fmt.Errorf("the value for %v is not an uint", k)
 	}
 	nv := rv + n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -397,20 +646,25 @@ func (c *cache[T]) IncrementUint(k string, n uint) (uint, error) {
 // IncrementUintptr increment an item of type uintptr by n. Returns an error if the item's value
 // is not an uintptr, or if it was not found. If there is no error, the
 // incremented value is returned.
-func (c *cache[T]) IncrementUintptr(k string, n uintptr) (uintptr, error) {
+func (c *cache[K, T]) IncrementUintptr(k K, n uintptr) (uintptr, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(uintptr)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an uintptr", k)
+		return 0, fmt.Errorf("the value for %v is not an uintptr", k)
 	}
 	nv := rv + n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -419,20 +673,25 @@ func (c *cache[T]) IncrementUintptr(k string, n uintptr) (uintptr, error) {
 // IncrementUint8 increment an item of type uint8 by n. Returns an error if the item's value
 // is not an uint8, or if it was not found. If there is no error, the
 // incremented value is returned.
-func (c *cache[T]) IncrementUint8(k string, n uint8) (uint8, error) {
+func (c *cache[K, T]) IncrementUint8(k K, n uint8) (uint8, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(uint8)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an uint8", k)
+		return 0, fmt.Errorf("the value for %v is not an uint8", k)
 	}
 	nv := rv + n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -441,20 +700,25 @@ func (c *cache[T]) IncrementUint8(k string, n uint8) (uint8, error) {
 // IncrementUint16 increment an item of type uint16 by n. Returns an error if the item's value
 // is not an uint16, or if it was not found. If there is no error, the
 // incremented value is returned.
-func (c *cache[T]) IncrementUint16(k string, n uint16) (uint16, error) {
+func (c *cache[K, T]) IncrementUint16(k K, n uint16) (uint16, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(uint16)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an uint16", k)
+		return 0, fmt.Errorf("the value for %v is not an uint16", k)
 	}
 	nv := rv + n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -463,20 +727,25 @@ func (c *cache[T]) IncrementUint16(k string, n uint16) (uint16, error) {
 // IncrementUint32 increment an item of type uint32 by n. Returns an error if the item's value
 // is not an uint32, or if it was not found. If there is no error, the
 // incremented value is returned.
-func (c *cache[T]) IncrementUint32(k string, n uint32) (uint32, error) {
+func (c *cache[K, T]) IncrementUint32(k K, n uint32) (uint32, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(uint32)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an uint32", k)
+		return 0, fmt.Errorf("the value for %v is not an uint32", k)
 	}
 	nv := rv + n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -485,20 +754,25 @@ func (c *cache[T]) IncrementUint32(k string, n uint32) (uint32, error) {
 // IncrementUint64 increment an item of type uint64 by n. Returns an error if the item's value
 // is not an uint64, or if it was not found. If there is no error, the
 // incremented value is returned.
-func (c *cache[T]) IncrementUint64(k string, n uint64) (uint64, error) {
+func (c *cache[K, T]) IncrementUint64(k K, n uint64) (uint64, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(uint64)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an uint64", k)
+		return 0, fmt.Errorf("the value for %v is not an uint64", k)
 	}
 	nv := rv + n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -507,20 +781,25 @@ func (c *cache[T]) IncrementUint64(k string, n uint64) (uint64, error) {
 // IncrementFloat32 increment an item of type float32 by n. Returns an error if the item's value
 // is not an float32, or if it was not found. If there is no error, the
 // incremented value is returned.
-func (c *cache[T]) IncrementFloat32(k string, n float32) (float32, error) {
+func (c *cache[K, T]) IncrementFloat32(k K, n float32) (float32, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(float32)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an float32", k)
+		return 0, fmt.Errorf("the value for %v is not an float32", k)
 	}
 	nv := rv + n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -529,20 +808,25 @@ func (c *cache[T]) IncrementFloat32(k string, n float32) (float32, error) {
 // IncrementFloat64 increment an item of type float64 by n. Returns an error if the item's value
 // is not an float64, or if it was not found. If there is no error, the
 // incremented value is returned.
-func (c *cache[T]) IncrementFloat64(k string, n float64) (float64, error) {
+func (c *cache[K, T]) IncrementFloat64(k K, n float64) (float64, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(float64)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an float64", k)
+		return 0, fmt.Errorf("the value for %v is not an float64", k)
 	}
 	nv := rv + n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -552,13 +836,21 @@ func (c *cache[T]) IncrementFloat64(k string, n float64) (float64, error) {
 // uint8, uint32, or uint64, float32 or float64 by n. Returns an error if the
 // item's value is not an integer, if it was not found, or if it is not
 // possible to decrement it by n. To retrieve the decremented value, use one
-// of the specialized methods, e.g. DecrementInt64.
-func (c *cache[T]) Decrement(k string, n int64) error {
+// of the specialized methods, e.g. DecrementInt64. Integers wrap around on
+// underflow; see DecrementWithMode for other ways of handling it.
+//
+// For a Cache of a numeric type, the Sub function is preferred over Decrement
+// and the typed methods such as DecrementInt64.
+func (c *cache[K, T]) Decrement(k K, n int64) error {
 	// TODO: Implement Increment and Decrement more cleanly.
 	// (Cannot do Increment(k, n*-1) for uints.)
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
 		return fmt.Errorf("item not found")
 	}
@@ -591,8 +883,9 @@ func (c *cache[T]) Decrement(k string, n int64) error {
 		v.SetValue(value - float64(n))
 	default:
 		c.mu.Unlock()
-		return fmt.Errorf("the value for %s is not an integer", k)
+		return fmt.Errorf("the value for %v is not an integer", k)
 	}
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nil
@@ -603,12 +896,16 @@ func (c *cache[T]) Decrement(k string, n int64) error {
 // possible to decrement it by n. Pass a negative number to decrement the
 // value. To retrieve the decremented value, use one of the specialized methods,
 // e.g. DecrementFloat64.
-func (c *cache[T]) DecrementFloat(k string, n float64) error {
+func (c *cache[K, T]) DecrementFloat(k K, n float64) error {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return fmt.Errorf("item %s not found", k)
+		return fmt.Errorf("item %v not found", k)
 	}
 	switch value := any(v.Object).(type) {
 	case float32:
@@ -617,8 +914,9 @@ func (c *cache[T]) DecrementFloat(k string, n float64) error {
 		v.SetValue(value - n)
 	default:
 		c.mu.Unlock()
-		return fmt.Errorf("the value for %s does not have type float32 or float64", k)
+		return fmt.Errorf("the value for %v does not have type float32 or float64", k)
 	}
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nil
@@ -627,20 +925,25 @@ func (c *cache[T]) DecrementFloat(k string, n float64) error {
 // DecrementInt decrement an item of type int by n. Returns an error if the item's value is
 // not an int, or if it was not found. If there is no error, the decremented
 // value is returned.
-func (c *cache[T]) DecrementInt(k string, n int) (int, error) {
+func (c *cache[K, T]) DecrementInt(k K, n int) (int, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(int)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an int", k)
+		return 0, fmt.Errorf("the value for %v is not an int", k)
 	}
 	nv := rv - n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -649,20 +952,25 @@ func (c *cache[T]) DecrementInt(k string, n int) (int, error) {
 // DecrementInt8 decrement an item of type int8 by n. Returns an error if the item's value is
 // not an int8, or if it was not found. If there is no error, the decremented
 // value is returned.
-func (c *cache[T]) DecrementInt8(k string, n int8) (int8, error) {
+func (c *cache[K, T]) DecrementInt8(k K, n int8) (int8, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(int8)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an int8", k)
+		return 0, fmt.Errorf("the value for %v is not an int8", k)
 	}
 	nv := rv - n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -671,20 +979,25 @@ func (c *cache[T]) DecrementInt8(k string, n int8) (int8, error) {
 // DecrementInt16 decrement an item of type int16 by n. Returns an error if the item's value is
 // not an int16, or if it was not found. If there is no error, the decremented
 // value is returned.
-func (c *cache[T]) DecrementInt16(k string, n int16) (int16, error) {
+func (c *cache[K, T]) DecrementInt16(k K, n int16) (int16, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(int16)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an int16", k)
+		return 0, fmt.Errorf("the value for %v is not an int16", k)
 	}
 	nv := rv - n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -693,20 +1006,25 @@ func (c *cache[T]) DecrementInt16(k string, n int16) (int16, error) {
 // DecrementInt32 decrement an item of type int32 by n. Returns an error if the item's value is
 // not an int32, or if it was not found. If there is no error, the decremented
 // value is returned.
-func (c *cache[T]) DecrementInt32(k string, n int32) (int32, error) {
+func (c *cache[K, T]) DecrementInt32(k K, n int32) (int32, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(int32)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an int32", k)
+		return 0, fmt.Errorf("the value for %v is not an int32", k)
 	}
 	nv := rv - n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -715,20 +1033,25 @@ func (c *cache[T]) DecrementInt32(k string, n int32) (int32, error) {
 // DecrementInt64 decrement an item of type int64 by n. Returns an error if the item's value is
 // not an int64, or if it was not found. If there is no error, the decremented
 // value is returned.
-func (c *cache[T]) DecrementInt64(k string, n int64) (int64, error) {
+func (c *cache[K, T]) DecrementInt64(k K, n int64) (int64, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(int64)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an int64", k)
+		return 0, fmt.Errorf("the value for %v is not an int64", k)
 	}
 	nv := rv - n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -737,20 +1060,25 @@ func (c *cache[T]) DecrementInt64(k string, n int64) (int64, error) {
 // DecrementUint decrement an item of type uint by n. Returns an error if the item's value is
 // not an uint, or if it was not found. If there is no error, the decremented
 // value is returned.
-func (c *cache[T]) DecrementUint(k string, n uint) (uint, error) {
+func (c *cache[K, T]) DecrementUint(k K, n uint) (uint, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(uint)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an uint", k)
+		return 0, fmt.Errorf("the value for %v is not an uint", k)
 	}
 	nv := rv - n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -759,20 +1087,25 @@ func (c *cache[T]) DecrementUint(k string, n uint) (uint, error) {
 // DecrementUintptr decrement an item of type uintptr by n. Returns an error if the item's value
 // is not an uintptr, or if it was not found. If there is no error, the
 // decremented value is returned.
-func (c *cache[T]) DecrementUintptr(k string, n uintptr) (uintptr, error) {
+func (c *cache[K, T]) DecrementUintptr(k K, n uintptr) (uintptr, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(uintptr)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an uintptr", k)
+		return 0, fmt.Errorf("the value for %v is not an uintptr", k)
 	}
 	nv := rv - n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -781,20 +1114,25 @@ func (c *cache[T]) DecrementUintptr(k string, n uintptr) (uintptr, error) {
 // DecrementUint8 decrement an item of type uint8 by n. Returns an error if the item's value is
 // not an uint8, or if it was not found. If there is no error, the decremented
 // value is returned.
-func (c *cache[T]) DecrementUint8(k string, n uint8) (uint8, error) {
+func (c *cache[K, T]) DecrementUint8(k K, n uint8) (uint8, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(uint8)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an uint8", k)
+		return 0, fmt.Errorf("the value for %v is not an uint8", k)
 	}
 	nv := rv - n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -803,20 +1141,25 @@ func (c *cache[T]) DecrementUint8(k string, n uint8) (uint8, error) {
 // DecrementUint16 decrement decrement an item of type uint16 by n. Returns an error if the item's value
 // is not an uint16, or if it was not found. If there is no error, the
 // decremented value is returned.
-func (c *cache[T]) DecrementUint16(k string, n uint16) (uint16, error) {
+func (c *cache[K, T]) DecrementUint16(k K, n uint16) (uint16, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(uint16)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an uint16", k)
+		return 0, fmt.Errorf("the value for %v is not an uint16", k)
 	}
 	nv := rv - n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -825,20 +1168,25 @@ func (c *cache[T]) DecrementUint16(k string, n uint16) (uint16, error) {
 // DecrementUint32 decrement an item of type uint32 by n. Returns an error if the item's value
 // is not an uint32, or if it was not found. If there is no error, the
 // decremented value is returned.
-func (c *cache[T]) DecrementUint32(k string, n uint32) (uint32, error) {
+func (c *cache[K, T]) DecrementUint32(k K, n uint32) (uint32, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(uint32)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an uint32", k)
+		return 0, fmt.Errorf("the value for %v is not an uint32", k)
 	}
 	nv := rv - n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -847,20 +1195,25 @@ func (c *cache[T]) DecrementUint32(k string, n uint32) (uint32, error) {
 // DecrementUint64 decrement an item of type uint64 by n. Returns an error if the item's value
 // is not an uint64, or if it was not found. If there is no error, the
 // decremented value is returned.
-func (c *cache[T]) DecrementUint64(k string, n uint64) (uint64, error) {
+func (c *cache[K, T]) DecrementUint64(k K, n uint64) (uint64, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(uint64)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an uint64", k)
+		return 0, fmt.Errorf("the value for %v is not an uint64", k)
 	}
 	nv := rv - n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -869,20 +1222,25 @@ func (c *cache[T]) DecrementUint64(k string, n uint64) (uint64, error) {
 // DecrementFloat32 decrement an item of type float32 by n. Returns an error if the item's value
 // is not an float32, or if it was not found. If there is no error, the
 // decremented value is returned.
-func (c *cache[T]) DecrementFloat32(k string, n float32) (float32, error) {
+func (c *cache[K, T]) DecrementFloat32(k K, n float32) (float32, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(float32)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an float32", k)
+		return 0, fmt.Errorf("the value for %v is not an float32", k)
 	}
 	nv := rv - n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
@@ -891,76 +1249,91 @@ func (c *cache[T]) DecrementFloat32(k string, n float32) (float32, error) {
 // DecrementFloat64 decrement an item of type float64 by n. Returns an error if the item's value
 // is not an float64, or if it was not found. If there is no error, the
 // decremented value is returned.
-func (c *cache[T]) DecrementFloat64(k string, n float64) (float64, error) {
+func (c *cache[K, T]) DecrementFloat64(k K, n float64) (float64, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
 	v, found := c.items[k]
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("item %s not found", k)
+		return 0, fmt.Errorf("item %v not found", k)
 	}
 	rv, ok := any(v.Object).(float64)
 	if !ok {
 		c.mu.Unlock()
-		return 0, fmt.Errorf("the value for %s is not an float64", k)
+		return 0, fmt.Errorf("the value for %v is not an float64", k)
 	}
 	nv := rv - n
 	v.SetValue(nv)
+	v.Version = c.nextVersion()
 	c.items[k] = v
 	c.mu.Unlock()
 	return nv, nil
 }
 
 // Delete an item from the cache. Does nothing if the key is not in the cache.
-func (c *cache[T]) Delete(k string) {
+func (c *cache[K, T]) Delete(k K) {
 	c.mu.Lock()
-	v, evicted := c.delete(k)
+	v, found := c.delete(k)
+	observed := c.observesEvictions()
 	c.mu.Unlock()
-	if evicted {
-		c.onEvicted(k, v)
-	}
-}
-
-func (c *cache[T]) delete(k string) (T, bool) {
-	if c.onEvicted != nil {
-		if v, found := c.items[k]; found {
-			delete(c.items, k)
-			return v.Object, true
+	if found {
+		c.stats.deletes.Add(1)
+		if observed {
+			c.evicted([]evictedItem[K, T]{{k, v, Deleted}})
 		}
 	}
-	delete(c.items, k)
-	var zero T
-	return zero, false
 }
 
-type keyAndValue[T any] struct {
-	key   string
-	value T
+// delete removes k from the cache, and returns the removed item and whether
+// it was found.
+func (c *cache[K, T]) delete(k K) (*Item[T], bool) {
+	c.expirations.remove(k)
+	if c.policy != nil {
+		c.policy.remove(k)
+	}
+	if c.index != nil {
+		c.index.remove(k)
+	}
+	v, found := c.items[k]
+	if found {
+		delete(c.items, k)
+		c.cost -= v.Cost
+		c.untag(k, v)
+	}
+	return v, found
 }
 
-// DeleteExpired delete all expired items from the cache.
-func (c *cache[T]) DeleteExpired() {
-	var evictedItems []keyAndValue[T]
-	now := time.Now().UnixNano()
+// DeleteExpired delete all expired items from the cache. If the cache has a
+// RefreshPolicy, items are kept until their grace period is over.
+//
+// The cache keeps an index of items by expiration time, so the cost of
+// DeleteExpired is proportional to the number of expired items rather than to
+// the number of items in the cache.
+func (c *cache[K, T]) DeleteExpired() {
+	var evictedItems []evictedItem[K, T]
+	now := c.now()
 	c.mu.Lock()
-	for k, v := range c.items {
-		// "Inlining" of expired
-		if v.Expiration > 0 && now > v.Expiration {
-			ov, evicted := c.delete(k)
-			if evicted {
-				evictedItems = append(evictedItems, keyAndValue[T]{k, ov})
+	for _, k := range c.expirations.popExpired(now - c.refresh.grace()) {
+		ov, found := c.delete(k)
+		if found {
+			c.stats.expirations.Add(1)
+			if c.observesEvictions() {
+				evictedItems = append(evictedItems, evictedItem[K, T]{k, ov, Expired})
 			}
 		}
 	}
 	c.mu.Unlock()
-	for _, v := range evictedItems {
-		c.onEvicted(v.key, v.value)
-	}
+	c.evicted(evictedItems)
 }
 
 // OnEvicted Sets an (optional) function that is called with the key and value when an
-// item is evicted from the cache. (Including when it is deleted manually, but
-// not when it is overwritten.) Set to nil to disable.
-func (c *cache[T]) OnEvicted(f func(string, T)) {
+// item is evicted from the cache. (Including when it is deleted manually or to
+// keep a bounded cache within its limit, but not when it is overwritten or
+// flushed. See OnEvictedWithReason.) Set to nil to disable.
+func (c *cache[K, T]) OnEvicted(f func(K, T)) {
 	c.mu.Lock()
 	c.onEvicted = f
 	c.mu.Unlock()
@@ -970,7 +1343,7 @@ func (c *cache[T]) OnEvicted(f func(string, T)) {
 //
 // NOTE: This method is deprecated in favor of c.Items() and NewFrom() (see the
 // documentation for NewFrom().)
-func (c *cache[T]) Save(w io.Writer) (err error) {
+func (c *cache[K, T]) Save(w io.Writer) (err error) {
 	enc := gob.NewEncoder(w)
 	defer func() {
 		if x := recover(); x != nil {
@@ -991,7 +1364,7 @@ func (c *cache[T]) Save(w io.Writer) (err error) {
 //
 // NOTE: This method is deprecated in favor of c.Items() and NewFrom() (see the
 // documentation for NewFrom().)
-func (c *cache[T]) SaveFile(fName string) error {
+func (c *cache[K, T]) SaveFile(fName string) error {
 	fp, err := os.Create(fName)
 	if err != nil {
 		return err
@@ -1009,29 +1382,42 @@ func (c *cache[T]) SaveFile(fName string) error {
 //
 // NOTE: This method is deprecated in favor of c.Items() and NewFrom() (see the
 // documentation for NewFrom().)
-func (c *cache[T]) Load(r io.Reader) error {
+func (c *cache[K, T]) Load(r io.Reader) error {
 	dec := gob.NewDecoder(r)
-	items := map[string]*Item[T]{}
+	items := map[K]*Item[T]{}
 	err := dec.Decode(&items)
 	if err == nil {
-		c.mu.Lock()
-		defer c.mu.Unlock()
-		for k, v := range items {
-			ov, found := c.items[k]
-			if !found || ov.Expired() {
-				c.items[k] = v
-			}
-		}
+		err = c.load(items)
 	}
 	return err
 }
 
+// load adds the given items, excluding any items with keys that already exist
+// (and haven't expired) in the cache.
+func (c *cache[K, T]) load(items map[K]*Item[T]) error {
+	var evictedItems []evictedItem[K, T]
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	for k, v := range items {
+		ov, found := c.items[k]
+		if !found || c.expired(ov) {
+			evictedItems = append(evictedItems, c.insert(k, v)...)
+		}
+	}
+	c.mu.Unlock()
+	c.evicted(evictedItems)
+	return nil
+}
+
 // LoadFile Load and add cache items from the given filename, excluding any items with
 // keys that already exist in the current cache.
 //
 // NOTE: This method is deprecated in favor of c.Items() and NewFrom() (see the
 // documentation for NewFrom().)
-func (c *cache[T]) LoadFile(fName string) error {
+func (c *cache[K, T]) LoadFile(fName string) error {
 	fp, err := os.Open(fName)
 	if err != nil {
 		return err
@@ -1045,11 +1431,11 @@ func (c *cache[T]) LoadFile(fName string) error {
 }
 
 // Items Copies all unexpired items in the cache into a new map and returns it.
-func (c *cache[T]) Items() map[string]*Item[T] {
+func (c *cache[K, T]) Items() map[K]*Item[T] {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	m := make(map[string]*Item[T], len(c.items))
-	now := time.Now().UnixNano()
+	m := make(map[K]*Item[T], len(c.items))
+	now := c.now()
 	for k, v := range c.items {
 		// "Inlining" of Expired
 		if v.Expiration > 0 {
@@ -1066,33 +1452,23 @@ func (c *cache[T]) Items() map[string]*Item[T] {
 // If f returns false, range stops the iteration.
 //
 // Range does not necessarily correspond to any consistent snapshot of the Map's
-// contents: no key will be visited more than once, but if the value for any key
-// is stored or deleted concurrently (including by f), Range may reflect any
-// mapping for that key from any point during the Range call. Range does not
-// block other methods on the receiver; even f itself may call any method on m.
+// contents: no key will be visited more than once, and keys that are added
+// after Range started (including by f) are not visited. Keys whose items are
+// changed concurrently are visited with the old or the new value, and items
+// that are deleted or expire concurrently may or may not be. Range only locks
+// the cache while it copies the next few items, so it does not block other
+// methods on the receiver while f runs; even f itself may call any method on
+// m.
 //
 // Range may be O(N) with the number of elements in the map even if f returns
 // false after a constant number of calls.
-func (c *cache[T]) Range(f func(key string, value T) bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	now := time.Now().UnixNano()
-	for k, v := range c.items {
-		// "Inlining" of Expired
-		if v.Expiration > 0 {
-			if now > v.Expiration {
-				continue
-			}
-		}
-		if !f(k, v.Object) {
-			break
-		}
-	}
+func (c *cache[K, T]) Range(f func(key K, value T) bool) {
+	c.all(f)
 }
 
 // ItemCount Returns the number of items in the cache. This may include items that have
 // expired, but have not yet been cleaned up.
-func (c *cache[T]) ItemCount() int {
+func (c *cache[K, T]) ItemCount() int {
 	c.mu.RLock()
 	n := len(c.items)
 	c.mu.RUnlock()
@@ -1100,65 +1476,140 @@ func (c *cache[T]) ItemCount() int {
 }
 
 // Flush Delete all items from the cache.
-func (c *cache[T]) Flush() {
+func (c *cache[K, T]) Flush() {
+	var evictedItems []evictedItem[K, T]
 	c.mu.Lock()
-	c.items = map[string]*Item[T]{}
-	c.mu.Unlock()
-}
-
-type janitor[T any] struct {
-	Interval time.Duration
-	stop     chan bool
-}
-
-func (j *janitor[T]) Run(c *cache[T]) {
-	ticker := time.NewTicker(j.Interval)
-	for {
-		select {
-		case <-ticker.C:
-			c.DeleteExpired()
-		case <-j.stop:
-			ticker.Stop()
-			return
+	if c.onEvictedReason != nil {
+		evictedItems = make([]evictedItem[K, T], 0, len(c.items))
+		for k, v := range c.items {
+			evictedItems = append(evictedItems, evictedItem[K, T]{k, v, Flushed})
 		}
 	}
+	c.stats.flushes.Add(uint64(len(c.items)))
+	c.items = map[K]*Item[T]{}
+	c.flushes++
+	c.cost = 0
+	c.expirations.clear()
+	if c.policy != nil {
+		c.policy.clear()
+	}
+	if c.index != nil {
+		c.index.clear()
+	}
+	c.tags = nil
+	c.mu.Unlock()
+	c.evicted(evictedItems)
 }
 
-func stopJanitor[T any](c *Cache[T]) {
-	c.janitor.stop <- true
+type janitor struct {
+	Interval time.Duration
+	stop     func()
 }
 
-func runJanitor[T any](c *cache[T], ci time.Duration) {
-	j := &janitor[T]{
+// runJanitor calls deleteExpired every ci according to clock, until the
+// returned janitor is stopped. Each run is counted in s.
+func runJanitor(clock Clock, ci time.Duration, deleteExpired func(), s *stats) *janitor {
+	return &janitor{
 		Interval: ci,
-		stop:     make(chan bool),
+		stop: clock.Tick(ci, func() {
+			start := clock.Now()
+			deleteExpired()
+			s.countJanitorRun(clock.Now().Sub(start))
+		}),
 	}
-	c.janitor = j
-	go j.Run(c)
 }
 
-func newCache[T any](de time.Duration, m map[string]*Item[T]) *cache[T] {
+func newCache[K comparable, T any](de time.Duration, m map[K]*Item[T], o *options) *cache[K, T] {
 	if de == 0 {
 		de = -1
 	}
-	c := &cache[T]{
+	c := &cache[K, T]{
 		defaultExpiration: de,
 		items:             m,
+		sliding:           o.sliding,
+		clock:             o.clock,
+	}
+	if o.costFunc != nil {
+		f, ok := o.costFunc.(func(T) int64)
+		if !ok {
+			panic(fmt.Sprintf("cache: WithCostFunc was passed a %T for a cache of %T", o.costFunc, *new(T)))
+		}
+		c.costFunc = f
+	}
+	if o.keyIndex {
+		idx, ok := any(newSkipList()).(keyIndex[K])
+		if !ok {
+			panic(fmt.Sprintf("cache: WithKeyIndex was passed for a cache with keys of type %T", *new(K)))
+		}
+		c.index = idx
+	}
+	for k, v := range m {
+		c.expirations.set(k, v.Expiration)
+		v.Version = c.nextVersion()
+		v.added = v.Version
+		if c.index != nil {
+			c.index.add(k)
+		}
+		c.tag(k, v)
+	}
+	if o.maxItems > 0 || o.maxCost > 0 {
+		c.maxItems = o.maxItems
+		c.maxCost = o.maxCost
+		c.policy = newPolicy[K](o.policy, o.maxItems)
+		for k, v := range m {
+			if c.maxCost > 0 {
+				if v.Cost < 1 {
+					v.Cost = c.costOf(v.Object)
+				}
+				c.cost += v.Cost
+			}
+			c.policy.add(k)
+		}
+		for c.overCapacity() {
+			k, _ := c.policy.evict()
+			c.delete(k)
+		}
 	}
 	return c
 }
 
-func newCacheWithJanitor[T any](de time.Duration, ci time.Duration, m map[string]*Item[T]) *Cache[T] {
-	c := newCache(de, m)
+func newCacheWithJanitor[T any](de time.Duration, ci time.Duration, m map[string]*Item[T], opts []Option) *Cache[T] {
+	o := newOptions(opts)
+	c := newCache(de, m, o)
 	// This trick ensures that the janitor goroutine (which--granted it
 	// was enabled--is running DeleteExpired on c forever) does not keep
 	// the returned C object from being garbage collected. When it is
-	// garbage collected, the finalizer stops the janitor goroutine, after
-	// which c can be collected.
+	// garbage collected, the finalizer closes c, which stops the janitor
+	// goroutine, after which c can be collected. Callers that don't want
+	// to wait for the garbage collector can call Close themselves.
 	C := &Cache[T]{c}
 	if ci > 0 {
-		runJanitor(c, ci)
-		runtime.SetFinalizer(C, stopJanitor[T])
+		c.janitor = runJanitor(c.clock, ci, c.DeleteExpired, &c.stats)
+		runtime.SetFinalizer(C, func(C *Cache[T]) {
+			_ = C.Close()
+		})
+	}
+	c.register(o.name)
+	if o.ctx != nil {
+		c.closeWhenDone(o.ctx)
+	}
+	return C
+}
+
+func newKeyedCacheWithJanitor[K comparable, T any](de time.Duration, ci time.Duration, m map[K]*Item[T], opts []Option) *KeyedCache[K, T] {
+	o := newOptions(opts)
+	c := newCache(de, m, o)
+	// See newCacheWithJanitor.
+	C := &KeyedCache[K, T]{c}
+	if ci > 0 {
+		c.janitor = runJanitor(c.clock, ci, c.DeleteExpired, &c.stats)
+		runtime.SetFinalizer(C, func(C *KeyedCache[K, T]) {
+			_ = C.Close()
+		})
+	}
+	c.register(o.name)
+	if o.ctx != nil {
+		c.closeWhenDone(o.ctx)
 	}
 	return C
 }
@@ -1168,9 +1619,15 @@ func newCacheWithJanitor[T any](de time.Duration, ci time.Duration, m map[string
 // the items in the cache never expire (by default), and must be deleted
 // manually. If the cleanup interval is less than one, expired items are not
 // deleted from the cache before calling c.DeleteExpired().
-func New[T any](defaultExpiration, cleanupInterval time.Duration) *Cache[T] {
+//
+// Optional behaviour, e.g. a limit on the number of items (WithMaxItems), can
+// be enabled by passing options.
+//
+// Call Close when the cache is no longer needed to stop its janitor right
+// away, rather than when the cache is garbage collected.
+func New[T any](defaultExpiration, cleanupInterval time.Duration, opts ...Option) *Cache[T] {
 	items := make(map[string]*Item[T])
-	return newCacheWithJanitor(defaultExpiration, cleanupInterval, items)
+	return newCacheWithJanitor(defaultExpiration, cleanupInterval, items, opts)
 }
 
 // NewFrom Return a new cache with a given default expiration duration and cleanup
@@ -1194,6 +1651,22 @@ func New[T any](defaultExpiration, cleanupInterval time.Duration) *Cache[T] {
 // gob.Register() the individual types stored in the cache before encoding a
 // map retrieved with c.Items(), and to register those same types before
 // decoding a blob containing an items map.
-func NewFrom[T any](defaultExpiration, cleanupInterval time.Duration, items map[string]*Item[T]) *Cache[T] {
-	return newCacheWithJanitor(defaultExpiration, cleanupInterval, items)
+//
+// If the cache is bounded (see WithMaxItems) and the map holds more items than
+// the limit, arbitrary items are removed from it until it fits.
+func NewFrom[T any](defaultExpiration, cleanupInterval time.Duration, items map[string]*Item[T], opts ...Option) *Cache[T] {
+	return newCacheWithJanitor(defaultExpiration, cleanupInterval, items, opts)
+}
+
+// NewKeyed Return a new cache with keys of type K. Otherwise it is the same as
+// New.
+func NewKeyed[K comparable, T any](defaultExpiration, cleanupInterval time.Duration, opts ...Option) *KeyedCache[K, T] {
+	items := make(map[K]*Item[T])
+	return newKeyedCacheWithJanitor(defaultExpiration, cleanupInterval, items, opts)
+}
+
+// NewKeyedFrom Return a new cache with keys of type K, using the given items map as
+// the underlying map. Otherwise it is the same as NewFrom.
+func NewKeyedFrom[K comparable, T any](defaultExpiration, cleanupInterval time.Duration, items map[K]*Item[T], opts ...Option) *KeyedCache[K, T] {
+	return newKeyedCacheWithJanitor(defaultExpiration, cleanupInterval, items, opts)
 }
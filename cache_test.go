@@ -1766,3 +1766,75 @@ func TestGetWithExpiration(t *testing.T) {
 		t.Error("expiration for e is in the past")
 	}
 }
+
+type testKey struct {
+	Tenant int
+	Name   string
+}
+
+func TestKeyedCache(t *testing.T) {
+	tc := NewKeyed[int, string](DefaultExpiration, 0)
+	tc.Set(1, "one", DefaultExpiration)
+	tc.Set(2, "two", DefaultExpiration)
+	if err := tc.Add(1, "uno", DefaultExpiration); err == nil {
+		t.Error("Successfully added another 1 when it should have returned an error")
+	}
+	x, found := tc.Get(1)
+	if !found {
+		t.Error("1 was not found")
+	}
+	if x != "one" {
+		t.Error("1 is not one:", x)
+	}
+	var evictedKey int
+	tc.OnEvicted(func(k int, v string) {
+		evictedKey = k
+	})
+	tc.Delete(2)
+	if evictedKey != 2 {
+		t.Error("evicted key is not 2:", evictedKey)
+	}
+	n := 0
+	tc.Range(func(k int, v string) bool {
+		if k != 1 || v != "one" {
+			t.Error("unexpected item in Range:", k, v)
+		}
+		n++
+		return true
+	})
+	if n != 1 {
+		t.Error("Range did not visit exactly one item:", n)
+	}
+}
+
+func TestKeyedCacheStructKey(t *testing.T) {
+	m := map[testKey]*Item[int]{
+		{1, "a"}: {Object: 1},
+	}
+	tc := NewKeyedFrom[testKey, int](DefaultExpiration, 0, m)
+	tc.Set(testKey{2, "a"}, 2, DefaultExpiration)
+	if _, err := tc.IncrementInt(testKey{1, "a"}, 2); err != nil {
+		t.Error("Couldn't increment {1 a}:", err)
+	}
+	x, found := tc.Get(testKey{1, "a"})
+	if !found || x != 3 {
+		t.Error("{1 a} is not 3:", x)
+	}
+	x, found = tc.Get(testKey{2, "a"})
+	if !found || x != 2 {
+		t.Error("{2 a} is not 2:", x)
+	}
+	if _, found = tc.Get(testKey{1, "b"}); found {
+		t.Error("{1 b} was found, but it was never set")
+	}
+}
+
+func BenchmarkKeyedCacheGetInt(b *testing.B) {
+	b.StopTimer()
+	tc := NewKeyed[int, string](DefaultExpiration, 0)
+	tc.Set(42, "bar", DefaultExpiration)
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		tc.Get(42)
+	}
+}
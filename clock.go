@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of time for a cache: it decides when items expire and
+// when the janitor runs. The default clock uses the system time; FakeClock
+// can be passed using WithClock to test expiration without sleeping.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// Tick calls f every d until the returned function is called.
+	Tick(d time.Duration, f func()) (stop func())
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Tick(d time.Duration, f func()) func() {
+	ticker := time.NewTicker(d)
+	stop := make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				f()
+			case <-stop:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+	}
+}
+
+// FakeClock is a Clock whose time only changes when Advance is called. Tick
+// functions are called synchronously by Advance, so once Advance returns, any
+// janitor runs that were due have completed.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+type fakeTicker struct {
+	interval time.Duration
+	next     time.Time
+	f        func()
+	stopped  bool
+}
+
+// NewFakeClock Return a FakeClock set to the given time.
+func NewFakeClock(t time.Time) *FakeClock {
+	return &FakeClock{now: t}
+}
+
+// Now returns the fake clock's current time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	t := c.now
+	c.mu.Unlock()
+	return t
+}
+
+// Tick calls f every d of fake time, from within Advance.
+func (c *FakeClock) Tick(d time.Duration, f func()) func() {
+	c.mu.Lock()
+	t := &fakeTicker{
+		interval: d,
+		next:     c.now.Add(d),
+		f:        f,
+	}
+	c.tickers = append(c.tickers, t)
+	c.mu.Unlock()
+	return func() {
+		c.mu.Lock()
+		t.stopped = true
+		for i, v := range c.tickers {
+			if v == t {
+				c.tickers = append(c.tickers[:i], c.tickers[i+1:]...)
+				break
+			}
+		}
+		c.mu.Unlock()
+	}
+}
+
+// Advance moves the fake clock forward by d. Every tick that falls within d
+// happens in order: the clock is set to the time of the tick, and the tick
+// function is called before Advance continues.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	for {
+		var next *fakeTicker
+		for _, t := range c.tickers {
+			if !t.next.After(end) && (next == nil || t.next.Before(next.next)) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+		c.now = next.next
+		next.next = next.next.Add(next.interval)
+		c.mu.Unlock()
+		next.f()
+		c.mu.Lock()
+	}
+	c.now = end
+	c.mu.Unlock()
+}
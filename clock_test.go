@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	var ticks []time.Time
+	stop := clock.Tick(10*time.Second, func() {
+		ticks = append(ticks, clock.Now())
+	})
+	clock.Advance(25 * time.Second)
+	if now := clock.Now(); !now.Equal(start.Add(25 * time.Second)) {
+		t.Error("clock was not advanced by 25s:", now)
+	}
+	if len(ticks) != 2 || !ticks[0].Equal(start.Add(10*time.Second)) || !ticks[1].Equal(start.Add(20*time.Second)) {
+		t.Error("ticks did not happen at 10s and 20s:", ticks)
+	}
+	stop()
+	clock.Advance(time.Minute)
+	if len(ticks) != 2 {
+		t.Error("tick function was called after it was stopped")
+	}
+}
+
+func TestCacheTimesFakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tc := New[int](50*time.Millisecond, time.Millisecond, WithClock(clock))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("b", 2, NoExpiration)
+	tc.Set("c", 3, 20*time.Millisecond)
+	tc.Set("d", 4, 70*time.Millisecond)
+
+	clock.Advance(25 * time.Millisecond)
+	if _, found := tc.Get("c"); found {
+		t.Error("Found c when it should have been automatically deleted")
+	}
+	if n := tc.ItemCount(); n != 3 {
+		t.Error("c was not deleted by the janitor; item count:", n)
+	}
+
+	clock.Advance(30 * time.Millisecond)
+	if _, found := tc.Get("a"); found {
+		t.Error("Found a when it should have been automatically deleted")
+	}
+	if _, found := tc.Get("b"); !found {
+		t.Error("Did not find b even though it was set to never expire")
+	}
+	if _, found := tc.Get("d"); !found {
+		t.Error("Did not find d even though it was set to expire later than the default")
+	}
+
+	clock.Advance(20 * time.Millisecond)
+	if _, found := tc.Get("d"); found {
+		t.Error("Found d when it should have been automatically deleted (later than the default)")
+	}
+	if n := tc.ItemCount(); n != 1 {
+		t.Error("Item count is not 1:", n)
+	}
+}
+
+func TestSlidingFakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tc := New[int](DefaultExpiration, 0, WithClock(clock))
+	tc.SetSliding("a", 1, time.Minute)
+	for i := 0; i < 10; i++ {
+		clock.Advance(50 * time.Second)
+		if _, found := tc.Get("a"); !found {
+			t.Fatal("a expired even though it was read within its sliding expiration")
+		}
+	}
+	clock.Advance(61 * time.Second)
+	if _, found := tc.Get("a"); found {
+		t.Error("a was found after it hasn't been read for its sliding expiration")
+	}
+}
+
+func TestShardedCacheFakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tc := NewSharded[int](time.Minute, time.Second, 4, WithClock(clock))
+	for i, k := range shardedKeys {
+		tc.Set(k, i, DefaultExpiration)
+	}
+	tc.Set("forever", 0, NoExpiration)
+	clock.Advance(time.Minute + time.Second)
+	if n := tc.ItemCount(); n != 1 {
+		t.Error("expired items were not deleted by the janitor; item count:", n)
+	}
+}
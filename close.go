@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrClosed is returned by methods that store or change items in a cache that
+// has been closed.
+var ErrClosed = errors.New("cache is closed")
+
+// Close stops the janitor, if any, and makes the cache reject all writes, so
+// that nothing is stored or changed any more: Set, SetDefault, SetSliding and
+// SetMulti do nothing, CompareAndSwap returns false, and Add, Replace, Load,
+// the Increment and Decrement methods and the Add and Sub functions return
+// ErrClosed. Items that are already in the cache can still be read and
+// deleted, and they still expire. So Compute and Update leave an item as it
+// is if their function returns a new value, but delete it if it returns
+// false. Close may be called more than once, and always returns nil.
+//
+// Caches are also closed when the context passed using WithContext is done.
+// Closing a cache created using WithName removes it from DefaultRegistry.
+func (c *cache[K, T]) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	j := c.janitor
+	stop := c.stopContext
+	c.mu.Unlock()
+	if j != nil {
+		j.stop()
+	}
+	if stop != nil {
+		stop()
+	}
+	if c.name != "" {
+		DefaultRegistry.unregister(c.name, c)
+	}
+	return nil
+}
+
+// closeWhenDone makes the cache close itself when ctx is done.
+func (c *cache[K, T]) closeWhenDone(ctx context.Context) {
+	c.mu.Lock()
+	c.stopContext = context.AfterFunc(ctx, func() {
+		_ = c.Close()
+	})
+	c.mu.Unlock()
+}
+
+// Close stops the janitor, if any, and closes all shards. See Cache.Close.
+func (sc *shardedCache[T]) Close() error {
+	sc.mu.Lock()
+	if sc.closed {
+		sc.mu.Unlock()
+		return nil
+	}
+	sc.closed = true
+	j := sc.janitor
+	stop := sc.stopContext
+	sc.mu.Unlock()
+	if j != nil {
+		j.stop()
+	}
+	if stop != nil {
+		stop()
+	}
+	for _, v := range sc.cs {
+		_ = v.Close()
+	}
+	if sc.name != "" {
+		DefaultRegistry.unregister(sc.name, sc)
+	}
+	return nil
+}
+
+// closeWhenDone makes the cache close itself when ctx is done.
+func (sc *shardedCache[T]) closeWhenDone(ctx context.Context) {
+	sc.mu.Lock()
+	sc.stopContext = context.AfterFunc(ctx, func() {
+		_ = sc.Close()
+	})
+	sc.mu.Unlock()
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestClose(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tc := New[int](DefaultExpiration, time.Second, WithClock(clock))
+	tc.Set("a", 1, DefaultExpiration)
+	if n := len(clock.tickers); n != 1 {
+		t.Fatal("janitor is not running; tickers:", n)
+	}
+	if err := tc.Close(); err != nil {
+		t.Error("Close returned an error:", err)
+	}
+	if err := tc.Close(); err != nil {
+		t.Error("second Close returned an error:", err)
+	}
+	if n := len(clock.tickers); n != 0 {
+		t.Error("janitor was not stopped; tickers:", n)
+	}
+
+	tc.Set("b", 2, DefaultExpiration)
+	if _, found := tc.Get("b"); found {
+		t.Error("b was stored after Close")
+	}
+	if err := tc.Add("c", 3, DefaultExpiration); err != ErrClosed {
+		t.Error("Add after Close did not return ErrClosed:", err)
+	}
+	if err := tc.Replace("a", 3, DefaultExpiration); err != ErrClosed {
+		t.Error("Replace after Close did not return ErrClosed:", err)
+	}
+	if err := tc.Increment("a", 1); err != ErrClosed {
+		t.Error("Increment after Close did not return ErrClosed:", err)
+	}
+	if _, err := tc.IncrementInt("a", 1); err != ErrClosed {
+		t.Error("IncrementInt after Close did not return ErrClosed:", err)
+	}
+	if _, err := tc.DecrementWithMode("a", 1, Checked); err != ErrClosed {
+		t.Error("DecrementWithMode after Close did not return ErrClosed:", err)
+	}
+	if _, err := Add(tc, "a", 1); err != ErrClosed {
+		t.Error("Add after Close did not return ErrClosed:", err)
+	}
+	if x, found := tc.Get("a"); !found || x != 1 {
+		t.Error("a could not be read after Close")
+	}
+	tc.Delete("a")
+	if n := tc.ItemCount(); n != 0 {
+		t.Error("a could not be deleted after Close")
+	}
+}
+
+func TestCloseLoad(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	tc.Set("a", 1, DefaultExpiration)
+	fp := &bytes.Buffer{}
+	if err := tc.Save(fp); err != nil {
+		t.Fatal("Couldn't save cache to fp:", err)
+	}
+	oc := New[int](DefaultExpiration, 0)
+	_ = oc.Close()
+	if err := oc.Load(fp); err != ErrClosed {
+		t.Error("Load after Close did not return ErrClosed:", err)
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	tc := New[int](DefaultExpiration, time.Second, WithClock(clock), WithContext(ctx))
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for {
+		if err := tc.Add("a", 1, DefaultExpiration); err == ErrClosed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("cache was not closed when its context was cancelled")
+		}
+		<-time.After(time.Millisecond)
+	}
+	clock.mu.Lock()
+	n := len(clock.tickers)
+	clock.mu.Unlock()
+	if n != 0 {
+		t.Error("janitor was not stopped; tickers:", n)
+	}
+}
+
+func TestShardedCacheClose(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tc := NewSharded[int](DefaultExpiration, time.Second, 4, WithClock(clock), WithContext(ctx))
+	for _, v := range tc.cs {
+		if v.stopContext != nil {
+			t.Fatal("shard is closed by the context instead of the sharded cache")
+		}
+	}
+	_ = tc.Close()
+	_ = tc.Close()
+	if n := len(clock.tickers); n != 0 {
+		t.Error("janitor was not stopped; tickers:", n)
+	}
+	for _, k := range shardedKeys {
+		if err := tc.Add(k, 1, DefaultExpiration); err != ErrClosed {
+			t.Error("Add after Close did not return ErrClosed:", err)
+		}
+	}
+}
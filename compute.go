@@ -0,0 +1,136 @@
+package cache
+
+import "time"
+
+// Compute atomically replaces the item under k by the result of f. f is called
+// with the item's current value and true, or with the zero value and false if
+// there is no item or it has expired. If f returns true, its result is stored
+// with the returned expiration duration, as by Set. If it returns false, the
+// item is deleted, if there is one. Compute returns the value under k
+// afterwards, and whether there is one.
+//
+// f is called while the cache is locked, so it must not call any methods of
+// the cache. If f panics, the cache is unlocked and the item left unchanged.
+func (c *cache[K, T]) Compute(k K, f func(old T, found bool) (T, time.Duration, bool)) (T, bool) {
+	return c.compute(k, func(old *Item[T]) (*Item[T], bool) {
+		var x T
+		if old != nil {
+			x = old.Object
+		}
+		nx, d, keep := f(x, old != nil)
+		if !keep {
+			return nil, false
+		}
+		var e int64
+		var sd time.Duration
+		if d == DefaultExpiration {
+			d = c.defaultExpiration
+		}
+		if d > 0 {
+			e = c.clock.Now().Add(d).UnixNano()
+			if c.sliding {
+				sd = d
+			}
+		}
+		return &Item[T]{
+			Object:     nx,
+			Expiration: e,
+			Sliding:    sd,
+		}, true
+	})
+}
+
+// Update is the same as Compute, except that an existing item keeps its
+// expiration time (and sliding expiration, if any), its cost and its tags. New
+// items get the default expiration.
+func (c *cache[K, T]) Update(k K, f func(old T, found bool) (T, bool)) (T, bool) {
+	return c.compute(k, func(old *Item[T]) (*Item[T], bool) {
+		if old == nil {
+			var x T
+			nx, keep := f(x, false)
+			if !keep {
+				return nil, false
+			}
+			var e int64
+			var sd time.Duration
+			if c.defaultExpiration > 0 {
+				e = c.clock.Now().Add(c.defaultExpiration).UnixNano()
+				if c.sliding {
+					sd = c.defaultExpiration
+				}
+			}
+			return &Item[T]{
+				Object:     nx,
+				Expiration: e,
+				Sliding:    sd,
+			}, true
+		}
+		nx, keep := f(old.Object, true)
+		if !keep {
+			return nil, false
+		}
+		return &Item[T]{
+			Object:     nx,
+			Expiration: old.Expiration,
+			Sliding:    old.Sliding,
+			Cost:       old.Cost,
+			Tags:       old.Tags,
+		}, true
+	})
+}
+
+// compute replaces the item under k by the result of f, which is called with
+// the current item, or nil if there is none or it has expired, and deletes it
+// if f returns false. Nothing is stored if the cache is closed.
+func (c *cache[K, T]) compute(k K, f func(old *Item[T]) (*Item[T], bool)) (T, bool) {
+	evictedItems, x, keep := c.computeLocked(k, f)
+	c.evicted(evictedItems)
+	return x, keep
+}
+
+// computeLocked does the work of compute while holding the lock, which is
+// released even if f panics.
+func (c *cache[K, T]) computeLocked(k K, f func(old *Item[T]) (*Item[T], bool)) ([]evictedItem[K, T], T, bool) {
+	var evictedItems []evictedItem[K, T]
+	var x T
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	old, found := c.items[k]
+	live := found && !c.expired(old)
+	var item *Item[T]
+	keep := false
+	if live {
+		item, keep = f(old)
+	} else {
+		item, keep = f(nil)
+	}
+	switch {
+	case keep && !c.closed:
+		evictedItems = c.insert(k, item)
+		// The item may have been evicted right away by a bounded cache.
+		if v, stored := c.items[k]; stored && v == item {
+			x = item.Object
+		} else {
+			keep = false
+		}
+	case keep:
+		// Closed caches don't store new items, so the old one stays.
+		keep = live
+		if live {
+			x = old.Object
+		}
+	case found:
+		c.delete(k)
+		reason := Deleted
+		if live {
+			c.stats.deletes.Add(1)
+		} else {
+			reason = Expired
+			c.stats.expirations.Add(1)
+		}
+		if c.observesEvictions() {
+			evictedItems = append(evictedItems, evictedItem[K, T]{k, old, reason})
+		}
+	}
+	return evictedItems, x, keep
+}
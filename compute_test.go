@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCompute(t *testing.T) {
+	tc := New[[]string](DefaultExpiration, 0)
+	x, found := tc.Compute("a", func(old []string, found bool) ([]string, time.Duration, bool) {
+		if found {
+			t.Error("missing item was found")
+		}
+		return append(old, "x"), DefaultExpiration, true
+	})
+	if !found || len(x) != 1 {
+		t.Error("Compute did not store a new item:", x, found)
+	}
+	x, found = tc.Compute("a", func(old []string, found bool) ([]string, time.Duration, bool) {
+		if !found || len(old) != 1 {
+			t.Error("existing item was not passed to f:", old, found)
+		}
+		return append(old, "y"), DefaultExpiration, true
+	})
+	if y, _ := tc.Get("a"); !found || len(x) != 2 || len(y) != 2 {
+		t.Error("Compute did not replace the item:", x, y)
+	}
+	var reason EvictionReason
+	tc.OnEvictedWithReason(func(k string, item Item[[]string], r EvictionReason) {
+		reason = r
+	})
+	x, found = tc.Compute("a", func(old []string, found bool) ([]string, time.Duration, bool) {
+		return nil, 0, false
+	})
+	if found || x != nil {
+		t.Error("Compute returned a deleted item:", x, found)
+	}
+	if _, found := tc.Get("a"); found {
+		t.Error("Compute did not delete the item")
+	}
+	if reason != Deleted {
+		t.Error("reason is not Deleted:", reason)
+	}
+	if s := tc.Stats(); s.Deletes != 1 {
+		t.Error("deletes are not 1:", s.Deletes)
+	}
+}
+
+func TestComputeExpired(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tc := New[int](DefaultExpiration, 0, WithClock(clock))
+	tc.Set("a", 1, time.Second)
+	clock.Advance(2 * time.Second)
+	tc.Compute("a", func(old int, found bool) (int, time.Duration, bool) {
+		if found {
+			t.Error("expired item was found")
+		}
+		return 0, 0, false
+	})
+	if n := tc.ItemCount(); n != 0 {
+		t.Error("expired item was not deleted")
+	}
+	if s := tc.Stats(); s.Expirations != 1 || s.Deletes != 0 {
+		t.Errorf("stats are %+v", s)
+	}
+	x, found := tc.Compute("b", func(old int, found bool) (int, time.Duration, bool) {
+		return 2, time.Second, true
+	})
+	if !found || x != 2 {
+		t.Error("Compute did not store b")
+	}
+	clock.Advance(2 * time.Second)
+	if _, found := tc.Get("b"); found {
+		t.Error("b did not expire")
+	}
+}
+
+func TestComputeConcurrent(t *testing.T) {
+	tc := New[map[string]int](DefaultExpiration, 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				tc.Compute("m", func(old map[string]int, found bool) (map[string]int, time.Duration, bool) {
+					m := map[string]int{"n": old["n"] + 1}
+					return m, DefaultExpiration, true
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	if m, _ := tc.Get("m"); m["n"] != 1000 {
+		t.Error("n is not 1000:", m["n"])
+	}
+}
+
+func TestComputeClosed(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Close()
+	x, found := tc.Compute("a", func(old int, found bool) (int, time.Duration, bool) {
+		return old + 1, DefaultExpiration, true
+	})
+	if !found || x != 1 {
+		t.Error("closed cache changed a:", x, found)
+	}
+	if _, found := tc.Compute("b", func(int, bool) (int, time.Duration, bool) {
+		return 1, DefaultExpiration, true
+	}); found {
+		t.Error("closed cache stored b")
+	}
+	if _, found := tc.Compute("a", func(int, bool) (int, time.Duration, bool) {
+		return 0, DefaultExpiration, false
+	}); found {
+		t.Error("Compute reported a as found after deleting it")
+	}
+	if _, found := tc.Get("a"); found {
+		t.Error("closed cache did not delete a")
+	}
+}
+
+func TestComputePanic(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	tc.Set("a", 1, DefaultExpiration)
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Update did not panic")
+			}
+		}()
+		tc.Update("a", func(int, bool) (int, bool) {
+			panic("boom")
+		})
+	}()
+	withTimeout(t, func() {
+		if x, found := tc.Get("a"); !found || x != 1 {
+			t.Error("a is", x, found)
+		}
+		tc.Set("b", 2, DefaultExpiration)
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tc := New[int](time.Minute, 0, WithClock(clock))
+	tc.Set("a", 1, time.Second)
+	_, e1, _ := tc.GetWithExpiration("a")
+	x, found := tc.Update("a", func(old int, found bool) (int, bool) {
+		return old + 1, true
+	})
+	if !found || x != 2 {
+		t.Error("Update did not replace a:", x, found)
+	}
+	if _, e2, _ := tc.GetWithExpiration("a"); !e1.Equal(e2) {
+		t.Error("Update changed the expiration time:", e1, e2)
+	}
+	tc.Update("b", func(old int, found bool) (int, bool) {
+		return 3, true
+	})
+	if _, e, found := tc.GetWithExpiration("b"); !found || !e.Equal(clock.Now().Add(time.Minute)) {
+		t.Error("new item did not get the default expiration:", e)
+	}
+	tc.Update("b", func(old int, found bool) (int, bool) {
+		return 0, false
+	})
+	if _, found := tc.Get("b"); found {
+		t.Error("Update did not delete b")
+	}
+	clock.Advance(2 * time.Second)
+	if _, found := tc.Get("a"); found {
+		t.Error("a did not expire after Update")
+	}
+}
+
+func TestUpdateCost(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0, WithMaxCost(100))
+	tc.SetWithCost("a", 1, DefaultExpiration, 60)
+	tc.Update("a", func(old int, found bool) (int, bool) {
+		return old + 1, true
+	})
+	if n := tc.TotalCost(); n != 60 {
+		t.Error("TotalCost is not 60:", n)
+	}
+	tc.SetWithCost("b", 1, DefaultExpiration, 60)
+	if _, found := tc.Get("a"); found {
+		t.Error("a was not evicted to make room for b")
+	}
+}
+
+func TestUpdateSliding(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tc := New[int](DefaultExpiration, 0, WithClock(clock))
+	tc.SetSliding("a", 1, time.Second)
+	tc.Update("a", func(old int, found bool) (int, bool) {
+		return old + 1, true
+	})
+	clock.Advance(time.Second / 2)
+	tc.Get("a")
+	clock.Advance(time.Second * 3 / 4)
+	if x, found := tc.Get("a"); !found || x != 2 {
+		t.Error("sliding expiration was not kept by Update")
+	}
+}
+
+func TestShardedCompute(t *testing.T) {
+	tc := NewSharded[int](DefaultExpiration, 0, 4)
+	for _, k := range shardedKeys {
+		tc.Compute(k, func(old int, found bool) (int, time.Duration, bool) {
+			return 1, DefaultExpiration, true
+		})
+		tc.Update(k, func(old int, found bool) (int, bool) {
+			return old + 1, true
+		})
+	}
+	for _, k := range shardedKeys {
+		if x, _ := tc.Get(k); x != 2 {
+			t.Error(k, "is not 2:", x)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package cache
+
+import "time"
+
+// Sizer is implemented by item types that know their own cost, e.g. their
+// size in bytes, for caches bounded by WithMaxCost.
+type Sizer interface {
+	Cost() int64
+}
+
+// SetWithCost Add an item to the cache, replacing any existing item, with the
+// given cost. It is the same as Set, except that a cache bounded by
+// WithMaxCost uses the given cost instead of computing one. If the cost is
+// less than one, it is computed as for Set.
+func (c *cache[K, T]) SetWithCost(k K, x T, d time.Duration, cost int64) {
+	var e int64
+	var sd time.Duration
+	if d == DefaultExpiration {
+		d = c.defaultExpiration
+	}
+	if d > 0 {
+		e = c.clock.Now().Add(d).UnixNano()
+		if c.sliding {
+			sd = d
+		}
+	}
+	var ic int64
+	if c.maxCost > 0 {
+		ic = cost
+	}
+	c.mu.Lock()
+	evictedItems := c.insert(k, &Item[T]{
+		Object:     x,
+		Expiration: e,
+		Sliding:    sd,
+		Cost:       ic,
+	})
+	c.mu.Unlock()
+	c.evicted(evictedItems)
+}
+
+// TotalCost Returns the total cost of the items in a cache bounded by
+// WithMaxCost, including expired items that have not yet been cleaned up.
+// It is zero for other caches.
+func (c *cache[K, T]) TotalCost() int64 {
+	c.mu.RLock()
+	n := c.cost
+	c.mu.RUnlock()
+	return n
+}
+
+// costOf computes the cost of x when it is stored without one.
+func (c *cache[K, T]) costOf(x T) int64 {
+	if c.costFunc != nil {
+		return c.costFunc(x)
+	}
+	if s, ok := any(x).(Sizer); ok {
+		return s.Cost()
+	}
+	return 1
+}
@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"testing"
+)
+
+type blob []byte
+
+func (b blob) Cost() int64 {
+	return int64(len(b))
+}
+
+func TestMaxCost(t *testing.T) {
+	tc := New[blob](DefaultExpiration, 0, WithMaxCost(10))
+	var evicted []string
+	tc.OnEvicted(func(k string, _ blob) {
+		evicted = append(evicted, k)
+	})
+	tc.Set("a", make(blob, 4), DefaultExpiration)
+	tc.Set("b", make(blob, 4), DefaultExpiration)
+	tc.Get("a")
+	tc.Set("c", make(blob, 4), DefaultExpiration)
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Fatal("evicted items are not [b]:", evicted)
+	}
+	if n := tc.TotalCost(); n != 8 {
+		t.Error("total cost is not 8:", n)
+	}
+	tc.Set("a", make(blob, 1), DefaultExpiration)
+	if n := tc.TotalCost(); n != 5 {
+		t.Error("total cost after replacing a is not 5:", n)
+	}
+	tc.Delete("c")
+	if n := tc.TotalCost(); n != 1 {
+		t.Error("total cost after deleting c is not 1:", n)
+	}
+	tc.Flush()
+	if n := tc.TotalCost(); n != 0 {
+		t.Error("total cost after Flush is not 0:", n)
+	}
+}
+
+func TestMaxCostTooLarge(t *testing.T) {
+	tc := New[blob](DefaultExpiration, 0, WithMaxCost(10))
+	tc.Set("a", make(blob, 4), DefaultExpiration)
+	tc.Set("b", make(blob, 11), DefaultExpiration)
+	if _, found := tc.Get("b"); found {
+		t.Error("item costing more than the budget was kept")
+	}
+	if _, found := tc.Get("a"); !found {
+		t.Error("item costing more than the budget evicted a")
+	}
+	if n := tc.TotalCost(); n != 4 {
+		t.Error("total cost is not 4:", n)
+	}
+}
+
+func TestSetWithCost(t *testing.T) {
+	tc := New[string](DefaultExpiration, 0, WithMaxCost(100))
+	tc.SetWithCost("a", "x", DefaultExpiration, 60)
+	tc.SetWithCost("b", "y", DefaultExpiration, 50)
+	if _, found := tc.Get("a"); found {
+		t.Error("a was not evicted")
+	}
+	tc.Set("c", "z", DefaultExpiration)
+	if n := tc.TotalCost(); n != 51 {
+		t.Error("total cost is not 51:", n)
+	}
+	uc := New[string](DefaultExpiration, 0)
+	uc.SetWithCost("a", "x", DefaultExpiration, 60)
+	if n := uc.TotalCost(); n != 0 {
+		t.Error("total cost of a cache without WithMaxCost is not 0:", n)
+	}
+}
+
+func TestCostFunc(t *testing.T) {
+	tc := New[string](DefaultExpiration, 0, WithMaxCost(10), WithCostFunc(func(s string) int64 {
+		return int64(len(s))
+	}))
+	tc.Set("a", "12345", DefaultExpiration)
+	tc.Set("b", "123456", DefaultExpiration)
+	if _, found := tc.Get("a"); found {
+		t.Error("a was not evicted")
+	}
+	if n := tc.TotalCost(); n != 6 {
+		t.Error("total cost is not 6:", n)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("cost function of the wrong type didn't panic")
+		}
+	}()
+	New[int](DefaultExpiration, 0, WithMaxCost(10), WithCostFunc(func(s string) int64 {
+		return 1
+	}))
+}
+
+func TestMaxCostAndMaxItems(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0, WithMaxCost(100), WithMaxItems(2))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("b", 2, DefaultExpiration)
+	tc.Set("c", 3, DefaultExpiration)
+	if n := tc.ItemCount(); n != 2 {
+		t.Error("item count is not 2:", n)
+	}
+	if n := tc.TotalCost(); n != 2 {
+		t.Error("total cost is not 2:", n)
+	}
+}
+
+func TestNewFromMaxCost(t *testing.T) {
+	items := map[string]*Item[blob]{
+		"a": {Object: make(blob, 6)},
+		"b": {Object: make(blob, 6)},
+		"c": {Object: make(blob, 6), Cost: 1},
+	}
+	tc := NewFrom(DefaultExpiration, 0, items, WithMaxCost(10))
+	if n := tc.TotalCost(); n > 10 {
+		t.Error("total cost is over budget:", n)
+	}
+	if n := tc.ItemCount(); n == 0 {
+		t.Error("all items were removed")
+	}
+}
+
+func TestShardedMaxCost(t *testing.T) {
+	tc := NewSharded[blob](DefaultExpiration, 0, 4, WithMaxCost(40))
+	for _, k := range shardedKeys {
+		tc.Set(k, make(blob, 5), DefaultExpiration)
+	}
+	if n := tc.TotalCost(); n > 40 {
+		t.Error("total cost is over budget:", n)
+	}
+	tc.SetWithCost("foo", nil, DefaultExpiration, 3)
+	if _, found := tc.Get("foo"); !found {
+		t.Error("foo was not stored")
+	}
+}
@@ -0,0 +1,302 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrOverflow is returned by Add, Sub, IncrementWithMode and
+// DecrementWithMode when the result does not fit in the item's type and the
+// OverflowMode is Checked.
+var ErrOverflow = errors.New("numeric overflow")
+
+// OverflowMode selects what happens when changing a numeric item would take
+// it beyond the bounds of its type.
+type OverflowMode int
+
+const (
+	// Checked leaves the item unchanged and returns ErrOverflow.
+	Checked OverflowMode = iota
+	// Saturate stores the largest or smallest value of the item's type
+	// instead (for floating point items, the largest finite one.)
+	Saturate
+	// Wrap stores the result of the ordinary Go arithmetic, which wraps
+	// around for integers and is infinite for floating point numbers. This
+	// is what Increment and Decrement do.
+	Wrap
+)
+
+// Number is the set of types whose items can be changed using Add and Sub.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// AddOption configures a single call to Add or Sub.
+type AddOption func(*addOptions)
+
+type addOptions struct {
+	init bool
+	d    time.Duration
+	mode OverflowMode
+}
+
+// InitMissing makes Add and Sub treat a missing or expired item as zero, and
+// store the result using the expiration duration d (which, like for Set, may
+// be DefaultExpiration or NoExpiration), instead of returning an error.
+func InitMissing(d time.Duration) AddOption {
+	return func(o *addOptions) {
+		o.init = true
+		o.d = d
+	}
+}
+
+// WithOverflow makes Add and Sub handle overflow according to mode. The
+// default is Checked.
+func WithOverflow(mode OverflowMode) AddOption {
+	return func(o *addOptions) {
+		o.mode = mode
+	}
+}
+
+// Add adds delta to the item under k and returns the result. The item keeps
+// its expiration time. Add returns an error if the item was not found (unless
+// InitMissing is given), or ErrOverflow if the result does not fit in N, in
+// which case the item is left unchanged. See WithOverflow for other ways of
+// handling overflow. Floating point items overflow when the result is
+// infinite.
+func Add[N Number](c *Cache[N], k string, delta N, opts ...AddOption) (N, error) {
+	return addTo(c.cache, k, opts, func(x N, mode OverflowMode) (N, error) {
+		return change(x, delta, false, mode)
+	})
+}
+
+// Sub subtracts delta from the item under k and returns the result. It is the
+// same as Add otherwise, and is needed to decrement items of unsigned types.
+func Sub[N Number](c *Cache[N], k string, delta N, opts ...AddOption) (N, error) {
+	return addTo(c.cache, k, opts, func(x N, mode OverflowMode) (N, error) {
+		return change(x, delta, true, mode)
+	})
+}
+
+// addTo replaces the item under k by the result of op, unless op returns an
+// error.
+func addTo[K comparable, N Number](c *cache[K, N], k K, opts []AddOption, op func(x N, mode OverflowMode) (N, error)) (N, error) {
+	var o addOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
+	v, found := c.items[k]
+	if !found || c.expired(v) {
+		if !o.init {
+			c.mu.Unlock()
+			return 0, fmt.Errorf("item %v not found", k)
+		}
+		r, err := op(0, o.mode)
+		if err != nil {
+			c.mu.Unlock()
+			return 0, fmt.Errorf("changing %v: %w", k, err)
+		}
+		evictedItems := c.set(k, r, o.d)
+		c.mu.Unlock()
+		c.evicted(evictedItems)
+		return r, nil
+	}
+	r, err := op(v.Object, o.mode)
+	if err != nil {
+		c.mu.Unlock()
+		return 0, fmt.Errorf("changing %v: %w", k, err)
+	}
+	c.replaceValue(k, v, r)
+	c.mu.Unlock()
+	return r, nil
+}
+
+// IncrementWithMode increments an item of type int, int8, int16, int32,
+// int64, uintptr, uint, uint8, uint16, uint32, uint64, float32 or float64 by
+// n, handling overflow according to mode, and returns the result. Returns an
+// error if the item's value is not a number, if it was not found, or
+// ErrOverflow if the result does not fit in the item's type and mode is
+// Checked.
+func (c *cache[K, T]) IncrementWithMode(k K, n int64, mode OverflowMode) (T, error) {
+	m, neg := magnitude(n)
+	return c.changeWithMode(k, m, neg, mode)
+}
+
+// DecrementWithMode decrements an item by n. It is the same as
+// IncrementWithMode otherwise.
+func (c *cache[K, T]) DecrementWithMode(k K, n int64, mode OverflowMode) (T, error) {
+	m, neg := magnitude(n)
+	return c.changeWithMode(k, m, !neg, mode)
+}
+
+// changeWithMode adds m to the item under k, or subtracts it if neg is true.
+func (c *cache[K, T]) changeWithMode(k K, m uint64, neg bool, mode OverflowMode) (T, error) {
+	var zero T
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return zero, ErrClosed
+	}
+	v, found := c.items[k]
+	if !found || c.expired(v) {
+		c.mu.Unlock()
+		return zero, fmt.Errorf("item %v not found", k)
+	}
+	f := float64(m)
+	if neg {
+		f = -f
+	}
+	var r any
+	var err error
+	switch value := any(v.Object).(type) {
+	case int:
+		r, err = changeInt(value, m, neg, mode)
+	case int8:
+		r, err = changeInt(value, m, neg, mode)
+	case int16:
+		r, err = changeInt(value, m, neg, mode)
+	case int32:
+		r, err = changeInt(value, m, neg, mode)
+	case int64:
+		r, err = changeInt(value, m, neg, mode)
+	case uint:
+		r, err = changeInt(value, m, neg, mode)
+	case uintptr:
+		r, err = changeInt(value, m, neg, mode)
+	case uint8:
+		r, err = changeInt(value, m, neg, mode)
+	case uint16:
+		r, err = changeInt(value, m, neg, mode)
+	case uint32:
+		r, err = changeInt(value, m, neg, mode)
+	case uint64:
+		r, err = changeInt(value, m, neg, mode)
+	case float32:
+		r, err = changeFloat(value, float32(f), mode)
+	case float64:
+		r, err = changeFloat(value, f, mode)
+	default:
+		c.mu.Unlock()
+		return zero, fmt.Errorf("the value for %v is not a number", k)
+	}
+	if err != nil {
+		c.mu.Unlock()
+		return zero, fmt.Errorf("changing %v: %w", k, err)
+	}
+	x := r.(T)
+	c.replaceValue(k, v, x)
+	c.mu.Unlock()
+	return x, nil
+}
+
+// replaceValue replaces v, the item under k, by an item with the value x and
+// the same expiration time and tags. The item is replaced rather than changed
+// in place, so that readers holding the old one don't race with this write.
+func (c *cache[K, T]) replaceValue(k K, v *Item[T], x T) {
+	c.items[k] = &Item[T]{
+		Object:     x,
+		Expiration: v.Expiration,
+		Sliding:    v.Sliding,
+		Cost:       v.Cost,
+		Version:    c.nextVersion(),
+		Tags:       v.Tags,
+		added:      v.added,
+	}
+}
+
+// change adds delta to x, or subtracts it if sub is true.
+func change[N Number](x, delta N, sub bool, mode OverflowMode) (N, error) {
+	if isFloat[N]() {
+		if sub {
+			delta = -delta
+		}
+		return changeFloat(x, delta, mode)
+	}
+	m, neg := magnitude(delta)
+	if sub {
+		neg = !neg
+	}
+	return changeInt(x, m, neg, mode)
+}
+
+// changeInt adds m to the integer x, or subtracts it if neg is true.
+func changeInt[N Number](x N, m uint64, neg bool, mode OverflowMode) (N, error) {
+	lo, hi := bounds[N]()
+	// Conversions to uint64 wrap around, so room is right even for the
+	// full range of int64.
+	r, limit, room := x+N(m), hi, uint64(hi)-uint64(x)
+	if neg {
+		r, limit, room = x-N(m), lo, uint64(x)-uint64(lo)
+	}
+	if m <= room || mode == Wrap {
+		return r, nil
+	}
+	if mode == Saturate {
+		return limit, nil
+	}
+	return x, ErrOverflow
+}
+
+// changeFloat adds delta to the floating point number x.
+func changeFloat[N Number](x, delta N, mode OverflowMode) (N, error) {
+	r := x + delta
+	if !math.IsInf(float64(r), 0) || math.IsInf(float64(x), 0) || math.IsInf(float64(delta), 0) || mode == Wrap {
+		return r, nil
+	}
+	if mode == Saturate {
+		if r > 0 {
+			return maxFloat[N](), nil
+		}
+		return -maxFloat[N](), nil
+	}
+	return x, ErrOverflow
+}
+
+// magnitude returns the absolute value of the integer n, and whether n is
+// negative.
+func magnitude[N Number](n N) (uint64, bool) {
+	if n < 0 {
+		return 0 - uint64(n), true
+	}
+	return uint64(n), false
+}
+
+// isFloat reports whether N is a floating point type.
+func isFloat[N Number]() bool {
+	var one N = 1
+	return one/2 != 0
+}
+
+// bounds returns the smallest and largest values of the integer type N.
+func bounds[N Number]() (N, N) {
+	// Find the largest power of two that fits in N.
+	x := N(1)
+	for x*2 > x {
+		x *= 2
+	}
+	var zero N
+	if zero-1 > 0 {
+		return 0, x + (x - 1)
+	}
+	return -x - x, x + (x - 1)
+}
+
+// maxFloat returns the largest finite value of the floating point type N.
+func maxFloat[N Number]() N {
+	third := 1.0 / 3
+	if float64(N(third)) != third {
+		m := math.MaxFloat32
+		return N(m)
+	}
+	m := math.MaxFloat64
+	return N(m)
+}
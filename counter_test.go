@@ -0,0 +1,235 @@
+package cache
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAddSub(t *testing.T) {
+	tc := New[int64](DefaultExpiration, 0)
+	if _, err := Add(tc, "n", 1); err == nil {
+		t.Error("Add did not fail for a missing item")
+	}
+	tc.Set("n", 1, DefaultExpiration)
+	if x, err := Add(tc, "n", 2); err != nil || x != 3 {
+		t.Error("Add returned", x, err)
+	}
+	if x, err := Sub(tc, "n", 5); err != nil || x != -2 {
+		t.Error("Sub returned", x, err)
+	}
+	if x, _ := tc.Get("n"); x != -2 {
+		t.Error("n is not -2:", x)
+	}
+}
+
+func TestAddKeepsExpiration(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tc := New[int](DefaultExpiration, 0, WithClock(clock))
+	tc.Set("n", 1, time.Second)
+	_, e1, _ := tc.GetWithExpiration("n")
+	Add(tc, "n", 1)
+	if _, e2, _ := tc.GetWithExpiration("n"); !e1.Equal(e2) {
+		t.Error("Add changed the expiration time:", e1, e2)
+	}
+	clock.Advance(2 * time.Second)
+	if _, err := Add(tc, "n", 1); err == nil {
+		t.Error("Add changed an expired item")
+	}
+}
+
+func TestAddInitMissing(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tc := New[uint](DefaultExpiration, 0, WithClock(clock))
+	if x, err := Add(tc, "n", 3, InitMissing(time.Second)); err != nil || x != 3 {
+		t.Error("Add did not initialize n:", x, err)
+	}
+	if x, err := Add(tc, "n", 3, InitMissing(time.Second)); err != nil || x != 6 {
+		t.Error("Add did not add to n:", x, err)
+	}
+	clock.Advance(2 * time.Second)
+	if _, found := tc.Get("n"); found {
+		t.Error("n did not expire")
+	}
+	if _, err := Sub(tc, "n", 1, InitMissing(DefaultExpiration)); !errors.Is(err, ErrOverflow) {
+		t.Error("Sub did not overflow below zero:", err)
+	}
+	tc.Close()
+	if _, err := Add(tc, "m", 1, InitMissing(DefaultExpiration)); err != ErrClosed {
+		t.Error("closed cache initialized m:", err)
+	}
+}
+
+func TestAddOverflow(t *testing.T) {
+	tu := New[uint8](DefaultExpiration, 0)
+	tu.Set("n", 255, DefaultExpiration)
+	if _, err := Add(tu, "n", 1); !errors.Is(err, ErrOverflow) {
+		t.Error("uint8 did not overflow:", err)
+	}
+	if x, _ := tu.Get("n"); x != 255 {
+		t.Error("overflowing Add changed n:", x)
+	}
+	tu.Set("n", 0, DefaultExpiration)
+	if _, err := Sub(tu, "n", 1); !errors.Is(err, ErrOverflow) {
+		t.Error("uint8 did not underflow:", err)
+	}
+
+	ti := New[int8](DefaultExpiration, 0)
+	ti.Set("n", 127, DefaultExpiration)
+	if _, err := Add(ti, "n", 1); !errors.Is(err, ErrOverflow) {
+		t.Error("int8 did not overflow:", err)
+	}
+	ti.Set("n", -128, DefaultExpiration)
+	if _, err := Add(ti, "n", -1); !errors.Is(err, ErrOverflow) {
+		t.Error("int8 did not underflow:", err)
+	}
+	if _, err := Sub(ti, "n", 1); !errors.Is(err, ErrOverflow) {
+		t.Error("int8 did not underflow:", err)
+	}
+	ti.Set("n", 0, DefaultExpiration)
+	if _, err := Sub(ti, "n", -128); !errors.Is(err, ErrOverflow) {
+		t.Error("int8 did not overflow:", err)
+	}
+	if x, err := Sub(ti, "n", -127); err != nil || x != 127 {
+		t.Error("Sub returned", x, err)
+	}
+
+	tf := New[float32](DefaultExpiration, 0)
+	tf.Set("n", math.MaxFloat32, DefaultExpiration)
+	if _, err := Add(tf, "n", math.MaxFloat32); !errors.Is(err, ErrOverflow) {
+		t.Error("float32 did not overflow:", err)
+	}
+	if x, err := Add(tf, "n", 0.5); err != nil || x != math.MaxFloat32 {
+		t.Error("Add returned", x, err)
+	}
+}
+
+func TestAddNamedType(t *testing.T) {
+	type count uint16
+	tc := New[count](DefaultExpiration, 0)
+	tc.Set("n", math.MaxUint16-1, DefaultExpiration)
+	if x, err := Add(tc, "n", 1); err != nil || x != math.MaxUint16 {
+		t.Error("Add returned", x, err)
+	}
+	if _, err := Add(tc, "n", 1); !errors.Is(err, ErrOverflow) {
+		t.Error("count did not overflow:", err)
+	}
+}
+
+func TestAddConcurrent(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				Add(tc, "n", 1, InitMissing(DefaultExpiration))
+				tc.Get("n")
+			}
+		}()
+	}
+	wg.Wait()
+	if x, _ := tc.Get("n"); x != 1000 {
+		t.Error("n is not 1000:", x)
+	}
+}
+
+func TestAddSaturate(t *testing.T) {
+	tc := New[uint8](DefaultExpiration, 0)
+	tc.Set("n", 250, DefaultExpiration)
+	if x, err := Add(tc, "n", 10, WithOverflow(Saturate)); err != nil || x != 255 {
+		t.Error("Add did not saturate:", x, err)
+	}
+	if x, err := Sub(tc, "n", 255, WithOverflow(Saturate)); err != nil || x != 0 {
+		t.Error("Sub returned", x, err)
+	}
+	if x, err := Sub(tc, "n", 1, WithOverflow(Saturate)); err != nil || x != 0 {
+		t.Error("Sub did not saturate:", x, err)
+	}
+	if x, err := Sub(tc, "n", 1, WithOverflow(Wrap)); err != nil || x != 255 {
+		t.Error("Sub did not wrap around:", x, err)
+	}
+
+	ti := New[int64](DefaultExpiration, 0)
+	ti.Set("n", math.MinInt64+1, DefaultExpiration)
+	if x, err := Add(ti, "n", -2, WithOverflow(Saturate)); err != nil || x != math.MinInt64 {
+		t.Error("Add did not saturate:", x, err)
+	}
+	if x, err := Sub(ti, "n", math.MinInt64, WithOverflow(Saturate)); err != nil || x != 0 {
+		t.Error("Sub returned", x, err)
+	}
+	if x, err := Sub(ti, "n", math.MinInt64, WithOverflow(Saturate)); err != nil || x != math.MaxInt64 {
+		t.Error("Sub did not saturate:", x, err)
+	}
+
+	tf := New[float64](DefaultExpiration, 0)
+	tf.Set("n", -math.MaxFloat64, DefaultExpiration)
+	if x, err := Sub(tf, "n", math.MaxFloat64, WithOverflow(Saturate)); err != nil || x != -math.MaxFloat64 {
+		t.Error("Sub did not saturate:", x, err)
+	}
+}
+
+func TestIncrementWithMode(t *testing.T) {
+	tc := New[any](DefaultExpiration, 0)
+	cases := []struct {
+		x, saturated any
+		n            int64
+	}{
+		{int(math.MaxInt), int(math.MaxInt), 1},
+		{int8(100), int8(math.MaxInt8), 100},
+		{int16(-3), int16(math.MinInt16), math.MinInt64},
+		{int32(math.MaxInt32), int32(math.MaxInt32), 1},
+		{int64(math.MaxInt64), int64(math.MaxInt64), math.MaxInt64},
+		{uint(1), uint(0), -2},
+		{uintptr(0), uintptr(0), -1},
+		{uint8(255), uint8(255), 1},
+		{uint16(0), uint16(math.MaxUint16), 1 << 40},
+		{uint32(5), uint32(0), math.MinInt64},
+		{uint64(math.MaxUint64), uint64(math.MaxUint64), 1},
+	}
+	for _, tt := range cases {
+		tc.Set("n", tt.x, DefaultExpiration)
+		if _, err := tc.IncrementWithMode("n", tt.n, Checked); !errors.Is(err, ErrOverflow) {
+			t.Errorf("%T did not overflow: %v", tt.x, err)
+		}
+		if x, _ := tc.Get("n"); x != tt.x {
+			t.Errorf("checked overflow changed %T: %v", tt.x, x)
+		}
+		if x, err := tc.IncrementWithMode("n", tt.n, Saturate); err != nil || x != tt.saturated {
+			t.Errorf("%T did not saturate: %v %v", tt.x, x, err)
+		}
+	}
+	tc.Set("n", uint8(255), DefaultExpiration)
+	if x, err := tc.IncrementWithMode("n", 1, Wrap); err != nil || x != uint8(0) {
+		t.Error("uint8 did not wrap around:", x, err)
+	}
+	if x, err := tc.DecrementWithMode("n", 1, Saturate); err != nil || x != uint8(0) {
+		t.Error("uint8 did not saturate:", x, err)
+	}
+	if x, err := tc.DecrementWithMode("n", -7, Checked); err != nil || x != uint8(7) {
+		t.Error("DecrementWithMode returned", x, err)
+	}
+	tc.Set("s", "x", DefaultExpiration)
+	if _, err := tc.IncrementWithMode("s", 1, Saturate); err == nil {
+		t.Error("string was incremented")
+	}
+	if _, err := tc.IncrementWithMode("missing", 1, Saturate); err == nil {
+		t.Error("missing item was incremented")
+	}
+}
+
+func TestShardedIncrementWithMode(t *testing.T) {
+	tc := NewSharded[int8](DefaultExpiration, 0, 4)
+	for _, k := range shardedKeys {
+		tc.Set(k, 120, DefaultExpiration)
+		if x, err := tc.IncrementWithMode(k, 10, Saturate); err != nil || x != math.MaxInt8 {
+			t.Error(k, "did not saturate:", x, err)
+		}
+		if _, err := tc.DecrementWithMode(k, 300, Checked); !errors.Is(err, ErrOverflow) {
+			t.Error(k, "did not overflow:", err)
+		}
+	}
+}
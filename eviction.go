@@ -0,0 +1,77 @@
+package cache
+
+// EvictionReason describes why an item was removed from a cache.
+type EvictionReason int
+
+const (
+	// Expired means the item expired and was deleted by DeleteExpired (or
+	// the janitor.)
+	Expired EvictionReason = iota + 1
+	// Deleted means the item was deleted manually, e.g. using Delete.
+	Deleted
+	// Replaced means the item was overwritten by another item with the same
+	// key, e.g. using Set or Replace.
+	Replaced
+	// Flushed means the item was deleted by Flush.
+	Flushed
+	// CapacityEvicted means the item was deleted to keep a bounded cache
+	// within its limit.
+	CapacityEvicted
+	// Invalidated means the item was deleted by InvalidateTag.
+	Invalidated
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case Expired:
+		return "expired"
+	case Deleted:
+		return "deleted"
+	case Replaced:
+		return "replaced"
+	case Flushed:
+		return "flushed"
+	case CapacityEvicted:
+		return "capacity"
+	case Invalidated:
+		return "invalidated"
+	}
+	return "unknown"
+}
+
+// evictedItem is an item that was removed from the cache while holding c.mu,
+// and must be passed to the eviction functions after releasing it.
+type evictedItem[K comparable, T any] struct {
+	key    K
+	item   *Item[T]
+	reason EvictionReason
+}
+
+// OnEvictedWithReason Sets an (optional) function that is called with the key,
+// a copy of the item and the reason whenever an item is removed from the
+// cache, including when it is overwritten or flushed. It is called in addition
+// to the function given to OnEvicted, if any. Set to nil to disable.
+func (c *cache[K, T]) OnEvictedWithReason(f func(k K, item Item[T], reason EvictionReason)) {
+	c.mu.Lock()
+	c.onEvictedReason = f
+	c.mu.Unlock()
+}
+
+// observesEvictions reports whether removed items need to be collected for
+// the eviction functions.
+func (c *cache[K, T]) observesEvictions() bool {
+	return c.onEvicted != nil || c.onEvictedReason != nil
+}
+
+// evicted calls the eviction functions for each of the given items. It must
+// be called without holding c.mu.
+func (c *cache[K, T]) evicted(evictedItems []evictedItem[K, T]) {
+	for _, v := range evictedItems {
+		if c.onEvicted != nil && v.reason != Replaced && v.reason != Flushed {
+			c.onEvicted(v.key, v.item.Object)
+		}
+		if c.onEvictedReason != nil {
+			c.onEvictedReason(v.key, *v.item, v.reason)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+type testEviction struct {
+	key    string
+	value  int
+	reason EvictionReason
+}
+
+func recordEvictions(tc *Cache[int]) *[]testEviction {
+	var evictions []testEviction
+	tc.OnEvictedWithReason(func(k string, item Item[int], reason EvictionReason) {
+		evictions = append(evictions, testEviction{k, item.Object, reason})
+	})
+	return &evictions
+}
+
+func TestOnEvictedWithReason(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0, WithMaxItems(3))
+	evictions := recordEvictions(tc)
+	var old []string
+	tc.OnEvicted(func(k string, v int) {
+		old = append(old, k)
+	})
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("a", 2, DefaultExpiration)
+	_ = tc.Replace("a", 3, DefaultExpiration)
+	tc.Set("b", 4, time.Millisecond)
+	tc.Set("c", 5, DefaultExpiration)
+	tc.Delete("c")
+	tc.Set("d", 6, DefaultExpiration)
+	tc.Set("e", 7, DefaultExpiration)
+	<-time.After(5 * time.Millisecond)
+	tc.DeleteExpired()
+	tc.Flush()
+	want := []testEviction{
+		{"a", 1, Replaced},
+		{"a", 2, Replaced},
+		{"c", 5, Deleted},
+		{"a", 3, CapacityEvicted},
+		{"b", 4, Expired},
+	}
+	if len(*evictions) != len(want)+2 {
+		t.Fatal("unexpected evictions:", *evictions)
+	}
+	for i, v := range want {
+		if (*evictions)[i] != v {
+			t.Errorf("eviction %d is %v, not %v", i, (*evictions)[i], v)
+		}
+	}
+	for _, v := range (*evictions)[len(want):] {
+		if v.reason != Flushed || (v.key != "d" && v.key != "e") {
+			t.Error("unexpected eviction by Flush:", v)
+		}
+	}
+	if len(old) != 3 || old[0] != "c" || old[1] != "a" || old[2] != "b" {
+		t.Error("OnEvicted function was not called for exactly [c a b]:", old)
+	}
+}
+
+func TestOnEvictedWithReasonItem(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	var evicted Item[int]
+	tc.OnEvictedWithReason(func(k string, item Item[int], reason EvictionReason) {
+		evicted = item
+	})
+	tc.Set("a", 1, time.Hour)
+	e := tc.items["a"].Expiration
+	tc.Delete("a")
+	if evicted.Object != 1 || evicted.Expiration != e {
+		t.Error("evicted item does not have the old value and expiration:", evicted)
+	}
+}
+
+func TestEvictionReasonString(t *testing.T) {
+	if s := CapacityEvicted.String(); s != "capacity" {
+		t.Error("CapacityEvicted is not capacity:", s)
+	}
+	if s := EvictionReason(0).String(); s != "unknown" {
+		t.Error("zero reason is not unknown:", s)
+	}
+}
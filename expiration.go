@@ -0,0 +1,88 @@
+package cache
+
+import "container/heap"
+
+// expirationIndex orders the keys of items that expire by their expiration
+// time, so that DeleteExpired can find expired items without looking at the
+// others. It is guarded by the cache's mutex.
+type expirationIndex[K comparable] struct {
+	h       expirationHeap[K]
+	entries map[K]*expirationEntry[K]
+}
+
+type expirationEntry[K comparable] struct {
+	key        K
+	expiration int64
+	index      int
+}
+
+// set records that k expires at e (in Unix nanoseconds), or, if e is not
+// positive, that it never expires.
+func (x *expirationIndex[K]) set(k K, e int64) {
+	if e <= 0 {
+		x.remove(k)
+		return
+	}
+	if en, found := x.entries[k]; found {
+		en.expiration = e
+		heap.Fix(&x.h, en.index)
+		return
+	}
+	if x.entries == nil {
+		x.entries = map[K]*expirationEntry[K]{}
+	}
+	en := &expirationEntry[K]{key: k, expiration: e}
+	x.entries[k] = en
+	heap.Push(&x.h, en)
+}
+
+func (x *expirationIndex[K]) remove(k K) {
+	if en, found := x.entries[k]; found {
+		heap.Remove(&x.h, en.index)
+		delete(x.entries, k)
+	}
+}
+
+// popExpired removes and returns the keys that expire before t.
+func (x *expirationIndex[K]) popExpired(t int64) []K {
+	var keys []K
+	for len(x.h) > 0 && x.h[0].expiration < t {
+		en := heap.Pop(&x.h).(*expirationEntry[K])
+		delete(x.entries, en.key)
+		keys = append(keys, en.key)
+	}
+	return keys
+}
+
+func (x *expirationIndex[K]) clear() {
+	x.h = nil
+	x.entries = nil
+}
+
+// expirationHeap implements heap.Interface.
+type expirationHeap[K comparable] []*expirationEntry[K]
+
+func (h expirationHeap[K]) Len() int { return len(h) }
+
+func (h expirationHeap[K]) Less(i, j int) bool { return h[i].expiration < h[j].expiration }
+
+func (h expirationHeap[K]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expirationHeap[K]) Push(x any) {
+	en := x.(*expirationEntry[K])
+	en.index = len(*h)
+	*h = append(*h, en)
+}
+
+func (h *expirationHeap[K]) Pop() any {
+	old := *h
+	n := len(old)
+	en := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return en
+}
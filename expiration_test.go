@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestExpirationIndex(t *testing.T) {
+	var x expirationIndex[string]
+	x.set("c", 30)
+	x.set("a", 10)
+	x.set("b", 20)
+	x.set("d", 40)
+	x.set("d", 0)
+	x.set("a", 25)
+	x.remove("c")
+	keys := x.popExpired(26)
+	if len(keys) != 2 || keys[0] != "b" || keys[1] != "a" {
+		t.Error("expired keys are not [b a]:", keys)
+	}
+	if keys = x.popExpired(100); len(keys) != 0 {
+		t.Error("keys that were removed or never expire were popped:", keys)
+	}
+}
+
+func TestDeleteExpiredIndex(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	tc.Set("a", 1, time.Millisecond)
+	tc.Set("b", 2, time.Millisecond)
+	tc.Set("c", 3, time.Millisecond)
+	tc.Set("d", 4, NoExpiration)
+	tc.Set("b", 2, NoExpiration)      // no longer expires
+	_ = tc.Replace("c", 3, time.Hour) // expires later
+	tc.Delete("a")                    // no longer in the cache
+	tc.Set("e", 5, time.Millisecond)
+	var evicted []string
+	tc.OnEvicted(func(k string, v int) {
+		evicted = append(evicted, k)
+	})
+	<-time.After(5 * time.Millisecond)
+	tc.DeleteExpired()
+	if len(evicted) != 1 || evicted[0] != "e" {
+		t.Error("evicted items are not [e]:", evicted)
+	}
+	if n := tc.ItemCount(); n != 3 {
+		t.Errorf("Item count is not 3: %d", n)
+	}
+
+	tc.Set("f", 6, time.Millisecond)
+	tc.Flush()
+	if n := len(tc.expirations.h); n != 0 {
+		t.Errorf("Expiration index has %d entries after Flush", n)
+	}
+}
+
+func TestDeleteExpiredNewFrom(t *testing.T) {
+	m := map[string]*Item[int]{
+		"a": {Object: 1, Expiration: time.Now().Add(-time.Second).UnixNano()},
+		"b": {Object: 2, Expiration: time.Now().Add(time.Hour).UnixNano()},
+		"c": {Object: 3},
+	}
+	tc := NewFrom[int](DefaultExpiration, 0, m)
+	tc.DeleteExpired()
+	if _, found := tc.items["a"]; found {
+		t.Error("a was not deleted")
+	}
+	if n := tc.ItemCount(); n != 2 {
+		t.Errorf("Item count is not 2: %d", n)
+	}
+}
+
+func BenchmarkDeleteExpiredFewExpired(b *testing.B) {
+	b.StopTimer()
+	tc := New[string](5*time.Minute, 0)
+	tc.mu.Lock()
+	for i := 0; i < 1000000; i++ {
+		tc.set(strconv.Itoa(i), "bar", DefaultExpiration)
+	}
+	tc.mu.Unlock()
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		tc.Set("expired", "bar", time.Nanosecond)
+		tc.DeleteExpired()
+	}
+}
@@ -0,0 +1,304 @@
+package cache
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrBadPattern is returned by Scan and DeleteMatching when the pattern is
+// malformed.
+var ErrBadPattern = errors.New("syntax error in pattern")
+
+// checkGlob returns ErrBadPattern if pattern is not a valid glob pattern. See
+// Cache.Scan for the syntax.
+func checkGlob(pattern string) error {
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			if i++; i == len(pattern) {
+				return ErrBadPattern
+			}
+		case '[':
+			n, ok := classLen(pattern[i:])
+			if !ok {
+				return ErrBadPattern
+			}
+			i += n - 1
+		}
+	}
+	return nil
+}
+
+// classLen returns the length of the character class at the start of p, and
+// whether it is valid.
+func classLen(p string) (int, bool) {
+	i := 1
+	if i < len(p) && (p[i] == '^' || p[i] == '!') {
+		i++
+	}
+	start := i
+	for i < len(p) && (p[i] != ']' || i == start) {
+		lo, n, ok := classChar(p[i:])
+		if !ok {
+			return 0, false
+		}
+		i += n
+		if i+1 < len(p) && p[i] == '-' && p[i+1] != ']' {
+			hi, n, ok := classChar(p[i+1:])
+			if !ok || hi < lo {
+				return 0, false
+			}
+			i += 1 + n
+		}
+	}
+	if i == len(p) {
+		return 0, false
+	}
+	return i + 1, true
+}
+
+// classChar decodes the possibly escaped character at the start of p, and
+// returns it and its length.
+func classChar(p string) (rune, int, bool) {
+	n := 0
+	if p[0] == '\\' {
+		if len(p) == 1 {
+			return 0, 0, false
+		}
+		n = 1
+	}
+	r, size := utf8.DecodeRuneInString(p[n:])
+	return r, n + size, true
+}
+
+// matchClass reports whether r is in the valid character class at the start
+// of p.
+func matchClass(p string, r rune) bool {
+	i := 1
+	negated := i < len(p) && (p[i] == '^' || p[i] == '!')
+	if negated {
+		i++
+	}
+	start := i
+	matched := false
+	for p[i] != ']' || i == start {
+		lo, n, _ := classChar(p[i:])
+		hi := lo
+		i += n
+		if p[i] == '-' && p[i+1] != ']' {
+			hi, n, _ = classChar(p[i+1:])
+			i += 1 + n
+		}
+		if lo <= r && r <= hi {
+			matched = true
+		}
+	}
+	return matched != negated
+}
+
+// matchGlob reports whether s matches the valid glob pattern.
+func matchGlob(pattern, s string) bool {
+	pi, si := 0, 0
+	// The position after the last star, and the position in s it is
+	// currently matched up to.
+	starP, starS := -1, 0
+	for si < len(s) {
+		if pi < len(pattern) {
+			if pattern[pi] == '*' {
+				pi++
+				starP, starS = pi, si
+				continue
+			}
+			r, size := utf8.DecodeRuneInString(s[si:])
+			ok := false
+			n := 1
+			switch pattern[pi] {
+			case '?':
+				ok = true
+			case '[':
+				n, _ = classLen(pattern[pi:])
+				ok = matchClass(pattern[pi:], r)
+			default:
+				var pr rune
+				pr, n, _ = classChar(pattern[pi:])
+				ok = pr == r
+			}
+			if ok {
+				pi += n
+				si += size
+				continue
+			}
+		}
+		if starP < 0 {
+			return false
+		}
+		// Let the last star match one more character.
+		_, size := utf8.DecodeRuneInString(s[starS:])
+		starS += size
+		pi, si = starP, starS
+	}
+	for pi < len(pattern) && pattern[pi] == '*' {
+		pi++
+	}
+	return pi == len(pattern)
+}
+
+// literalPrefix returns the part of pattern before its first special
+// character, which all matching keys start with.
+func literalPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
+
+// scanCount is the number of keys Scan examines if count is less than one.
+const scanCount = 10
+
+// scanChunk examines up to n keys in the range of keys that can match
+// pattern, after the key after if resume is true. It returns them in order,
+// whether each of them belongs to an unexpired item and matches, and whether
+// there may be more keys.
+func scanChunk[T any](c *cache[string, T], pattern, after string, resume bool, n int) ([]string, []bool, bool) {
+	c.mu.RLock()
+	keys, more := orderedKeys(c, prefixRange(literalPrefix(pattern)), after, resume, n)
+	now := c.now()
+	matched := make([]bool, len(keys))
+	for i, k := range keys {
+		v := c.items[k]
+		matched[i] = (v.Expiration == 0 || now <= v.Expiration) && matchGlob(pattern, k)
+	}
+	c.mu.RUnlock()
+	return keys, matched, more
+}
+
+// parseCursor returns the last key examined by the Scan call that returned
+// cursor, and false if the scan is just starting.
+func parseCursor(cursor string) (string, bool) {
+	if cursor == "" {
+		return "", false
+	}
+	return cursor[1:], true
+}
+
+// makeCursor returns a cursor to continue a scan after the key after. It is
+// never empty, even if after is.
+func makeCursor(after string) string {
+	return ">" + after
+}
+
+// Scan incrementally lists the keys of unexpired items that match the glob
+// pattern, like the Redis SCAN command with MATCH. Start with an empty
+// cursor, and pass the returned cursor to the next call until it is empty.
+// Each call examines about count keys (10 if count is less than one), and
+// returns those that match, which may be none, in key order. It returns
+// ErrBadPattern if the pattern is malformed.
+//
+// In a pattern, '*' matches any sequence of characters, '?' matches any
+// single character, '[abc]' and '[a-c]' match any of the given characters,
+// '[^abc]' or '[!abc]' any other character, and '\' escapes the character
+// after it.
+//
+// A cursor is only valid for the cache that returned it. Items that are
+// stored or deleted while a scan is in progress may or may not be returned,
+// but no key is returned twice. Each call only holds the read lock while it
+// finds its count keys. For caches created using WithKeyIndex, that takes
+// O(log n) time plus the time to examine them, and only keys that start with
+// the literal prefix of the pattern (e.g. "session:" for "session:*:flags")
+// are examined. Otherwise, every call goes through all keys.
+func (c *Cache[T]) Scan(cursor, pattern string, count int) ([]string, string, error) {
+	if err := checkGlob(pattern); err != nil {
+		return nil, "", err
+	}
+	if count < 1 {
+		count = scanCount
+	}
+	after, resume := parseCursor(cursor)
+	keys, matched, more := scanChunk(c.cache, pattern, after, resume, count)
+	var matches []string
+	for i, k := range keys {
+		if matched[i] {
+			matches = append(matches, k)
+		}
+	}
+	if !more {
+		return matches, "", nil
+	}
+	return matches, makeCursor(keys[len(keys)-1]), nil
+}
+
+// DeleteMatching Delete all items whose keys match the glob pattern (see
+// Scan), and return how many there were. For caches created using
+// WithKeyIndex, it locks the cache for a few items at a time, and calls the
+// eviction functions for them in between. Otherwise, like DeletePrefix, it
+// deletes all matching items while holding the lock, and then calls the
+// eviction functions. It returns ErrBadPattern if the pattern is malformed.
+func (c *Cache[T]) DeleteMatching(pattern string) (int, error) {
+	if err := checkGlob(pattern); err != nil {
+		return 0, err
+	}
+	return deleteRange(c.cache, prefixRange(literalPrefix(pattern)), func(k string) bool {
+		return matchGlob(pattern, k)
+	}), nil
+}
+
+// Scan incrementally lists the keys in all shards that match the glob
+// pattern, in key order. See Cache.Scan.
+func (sc *shardedCache[T]) Scan(cursor, pattern string, count int) ([]string, string, error) {
+	if err := checkGlob(pattern); err != nil {
+		return nil, "", err
+	}
+	if count < 1 {
+		count = scanCount
+	}
+	after, resume := parseCursor(cursor)
+	// Each shard returns its first count keys after the cursor, so all
+	// keys up to the count-th smallest of them have been examined.
+	type scanned struct {
+		k       string
+		matched bool
+	}
+	var all []scanned
+	more := false
+	for _, v := range sc.cs {
+		keys, matched, m := scanChunk(v, pattern, after, resume, count)
+		for i, k := range keys {
+			all = append(all, scanned{k, matched[i]})
+		}
+		more = more || m
+	}
+	slices.SortFunc(all, func(a, b scanned) int {
+		return strings.Compare(a.k, b.k)
+	})
+	if len(all) > count {
+		all = all[:count]
+		more = true
+	}
+	var matches []string
+	for _, s := range all {
+		if s.matched {
+			matches = append(matches, s.k)
+		}
+	}
+	if !more {
+		return matches, "", nil
+	}
+	return matches, makeCursor(all[len(all)-1].k), nil
+}
+
+// DeleteMatching Delete all items in all shards whose keys match the glob
+// pattern, and return how many there were. See Cache.DeleteMatching.
+func (sc *shardedCache[T]) DeleteMatching(pattern string) (int, error) {
+	if err := checkGlob(pattern); err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, v := range sc.cs {
+		n += deleteRange(v, prefixRange(literalPrefix(pattern)), func(k string) bool {
+			return matchGlob(pattern, k)
+		})
+	}
+	return n, nil
+}
@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMatchGlob(t *testing.T) {
+	cases := []struct {
+		pattern, s string
+		match      bool
+	}{
+		{"", "", true},
+		{"", "a", false},
+		{"*", "", true},
+		{"*", "anything:at/all", true},
+		{"session:*:flags", "session:42:flags", true},
+		{"session:*:flags", "session:42:7:flags", true},
+		{"session:*:flags", "session:42:flag", false},
+		{"tenant-?:*", "tenant-a:x", true},
+		{"tenant-?:*", "tenant-é:x", true},
+		{"tenant-?:*", "tenant-ab:x", false},
+		{"a*b*c", "aXbYbZc", true},
+		{"a*b*c", "aXbYbZ", false},
+		{"[abc]x", "bx", true},
+		{"[abc]x", "dx", false},
+		{"[a-c]x", "cx", true},
+		{"[^a-c]x", "cx", false},
+		{"[!a-c]x", "dx", true},
+		{"[]]", "]", true},
+		{"[a-]", "-", true},
+		{`\*`, "*", true},
+		{`\*`, "a", false},
+		{`[\]]`, "]", true},
+	}
+	for _, tt := range cases {
+		if err := checkGlob(tt.pattern); err != nil {
+			t.Errorf("checkGlob(%q) returned %v", tt.pattern, err)
+		}
+		if m := matchGlob(tt.pattern, tt.s); m != tt.match {
+			t.Errorf("matchGlob(%q, %q) is %v", tt.pattern, tt.s, m)
+		}
+	}
+	for _, p := range []string{"[", "[a", "[]", `\`, "a[b-a]", `[\`} {
+		if err := checkGlob(p); err != ErrBadPattern {
+			t.Errorf("checkGlob(%q) returned %v", p, err)
+		}
+	}
+}
+
+func TestLiteralPrefix(t *testing.T) {
+	for p, want := range map[string]string{
+		"session:*:flags": "session:",
+		"tenant-?:*":      "tenant-",
+		"abc":             "abc",
+		`a\*`:             "a",
+		"[ab]":            "",
+	} {
+		if got := literalPrefix(p); got != want {
+			t.Errorf("literalPrefix(%q) is %q", p, got)
+		}
+	}
+}
+
+// scanAll calls Scan until the cursor is empty, and checks that it is called
+// more than once.
+func scanAll(t *testing.T, scan func(cursor, pattern string, count int) ([]string, string, error), pattern string) []string {
+	t.Helper()
+	var keys []string
+	cursor := ""
+	calls := 0
+	for {
+		matches, next, err := scan(cursor, pattern, 7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, matches...)
+		calls++
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if calls < 2 {
+		t.Error("Scan returned all keys at once")
+	}
+	return keys
+}
+
+func testScan(t *testing.T, opts ...Option) {
+	clock := NewFakeClock(time.Now())
+	tc := New[int](DefaultExpiration, 0, append(opts, WithClock(clock))...)
+	for i := 0; i < 50; i++ {
+		tc.Set(fmt.Sprintf("session:%02d:flags", i), i, DefaultExpiration)
+		tc.Set(fmt.Sprintf("session:%02d:user", i), i, DefaultExpiration)
+		tc.Set(fmt.Sprintf("tenant-%c:%d", 'a'+i%5, i), i, DefaultExpiration)
+	}
+	tc.Set("session:xx:flags", 0, time.Second)
+	clock.Advance(2 * time.Second)
+	keys := scanAll(t, tc.Scan, "session:*:flags")
+	if len(keys) != 50 || !slices.IsSorted(keys) {
+		t.Error("Scan returned", len(keys), "keys")
+	}
+	keys = scanAll(t, tc.Scan, "tenant-[ab]:*")
+	if len(keys) != 20 {
+		t.Error("Scan returned", len(keys), "keys")
+	}
+	if _, _, err := tc.Scan("", "[", 0); err != ErrBadPattern {
+		t.Error("Scan did not reject a bad pattern:", err)
+	}
+	var evicted int
+	tc.OnEvicted(func(k string, x int) {
+		tc.Get(k)
+		evicted++
+	})
+	if n, err := tc.DeleteMatching("session:*:flags"); err != nil || n != 51 {
+		t.Error("DeleteMatching returned", n, err)
+	}
+	if evicted != 51 {
+		t.Error("OnEvicted was called for", evicted, "items")
+	}
+	if n := tc.ItemCount(); n != 100 {
+		t.Error("ItemCount is not 100:", n)
+	}
+}
+
+func TestScan(t *testing.T) {
+	testScan(t, WithKeyIndex())
+}
+
+func TestScanWithoutIndex(t *testing.T) {
+	testScan(t)
+}
+
+func TestScanLeavesIndex(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Scan("", "*", 0)
+	tc.DeleteMatching("b*")
+	if tc.index != nil {
+		t.Error("Scan or DeleteMatching gave the cache a key index")
+	}
+}
+
+func TestShardedScan(t *testing.T) {
+	for _, opts := range [][]Option{{WithKeyIndex()}, nil} {
+		tc := NewSharded[int](DefaultExpiration, 0, 4, opts...)
+		for i := 0; i < 50; i++ {
+			tc.Set(fmt.Sprintf("session:%02d:flags", i), i, DefaultExpiration)
+			tc.Set(fmt.Sprintf("session:%02d:user", i), i, DefaultExpiration)
+		}
+		keys := scanAll(t, tc.Scan, "session:*:flags")
+		if len(keys) != 50 || !slices.IsSorted(keys) {
+			t.Error("Scan returned", len(keys), "keys")
+		}
+		if n, err := tc.DeleteMatching("session:?[02468]:*"); err != nil || n != 50 {
+			t.Error("DeleteMatching returned", n, err)
+		}
+	}
+}
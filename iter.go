@@ -0,0 +1,133 @@
+package cache
+
+import "iter"
+
+// iterChunk is the number of items the iterators copy while holding the read
+// lock, before releasing it to yield them.
+const iterChunk = 64
+
+// All returns an iterator over the keys and values of the unexpired items in
+// the cache.
+//
+// The cache is only locked while the iterator copies the next few items, not
+// while they are yielded, so a slow loop body doesn't block other methods,
+// and may itself call any method of the cache. The consistency guarantees
+// are those of Range: no key is visited more than once, and items stored or
+// changed after the iteration started are not visited.
+func (c *cache[K, T]) All() iter.Seq2[K, T] {
+	return c.all
+}
+
+// Keys returns an iterator over the keys of the unexpired items in the cache.
+// See Range for its consistency guarantees.
+func (c *cache[K, T]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		c.all(func(k K, _ T) bool {
+			return yield(k)
+		})
+	}
+}
+
+// Values returns an iterator over the values of the unexpired items in the
+// cache. See Range for its consistency guarantees.
+func (c *cache[K, T]) Values() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		c.all(func(_ K, x T) bool {
+			return yield(x)
+		})
+	}
+}
+
+// all calls yield for each unexpired item until it returns false. It copies
+// iterChunk items at a time while holding the read lock, and calls yield for
+// them after releasing it.
+//
+// Keys that were added to the map after the iteration started may be skipped,
+// and skipping them ensures that a key that is deleted and stored again isn't
+// visited twice. Keys whose item is only replaced stay in the map, so they are
+// visited once. If the cache is flushed, the items
+// left in the old map have all been deleted, so the iteration ends.
+func (c *cache[K, T]) all(yield func(K, T) bool) {
+	type entry struct {
+		k K
+		x T
+	}
+	chunk := make([]entry, 0, iterChunk)
+	flush := func() bool {
+		for _, e := range chunk {
+			if !yield(e.k, e.x) {
+				return false
+			}
+		}
+		chunk = chunk[:0]
+		return true
+	}
+	c.mu.RLock()
+	now := c.now()
+	version, flushes := c.version, c.flushes
+	// Go allows maps to be changed between the steps of a range loop, so
+	// it can continue after the lock was released and taken again.
+	for k, v := range c.items {
+		if v.added > version || (v.Expiration > 0 && now > v.Expiration) {
+			continue
+		}
+		chunk = append(chunk, entry{k, v.Object})
+		if len(chunk) < iterChunk {
+			continue
+		}
+		c.mu.RUnlock()
+		if !flush() {
+			return
+		}
+		c.mu.RLock()
+		if c.flushes != flushes {
+			c.mu.RUnlock()
+			return
+		}
+		now = c.now()
+	}
+	c.mu.RUnlock()
+	flush()
+}
+
+// All returns an iterator over the keys and values of the unexpired items in
+// all shards, one shard at a time. See Cache.Range for its consistency
+// guarantees.
+func (sc *shardedCache[T]) All() iter.Seq2[string, T] {
+	return func(yield func(string, T) bool) {
+		for _, v := range sc.cs {
+			ok := true
+			v.all(func(k string, x T) bool {
+				ok = yield(k, x)
+				return ok
+			})
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the keys of the unexpired items in all
+// shards. See Cache.Range for its consistency guarantees.
+func (sc *shardedCache[T]) Keys() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for k := range sc.All() {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the values of the unexpired items in all
+// shards. See Cache.Range for its consistency guarantees.
+func (sc *shardedCache[T]) Values() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, x := range sc.All() {
+			if !yield(x) {
+				return
+			}
+		}
+	}
+}
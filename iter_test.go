@@ -0,0 +1,226 @@
+package cache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAll(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tc := New[int](DefaultExpiration, 0, WithClock(clock))
+	for i := 0; i < 200; i++ {
+		tc.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	tc.Set("expired", -1, time.Second)
+	clock.Advance(2 * time.Second)
+	seen := map[string]int{}
+	for k, x := range tc.All() {
+		if _, dup := seen[k]; dup {
+			t.Error(k, "was visited twice")
+		}
+		seen[k] = x
+	}
+	if len(seen) != 200 {
+		t.Error("All visited", len(seen), "items")
+	}
+	for k, x := range seen {
+		if k != strconv.Itoa(x) {
+			t.Error(k, "has the value", x)
+		}
+	}
+	n := 0
+	for range tc.Keys() {
+		n++
+		if n == 100 {
+			break
+		}
+	}
+	if n != 100 {
+		t.Error("Keys did not stop after 100 items:", n)
+	}
+	sum := 0
+	for x := range tc.Values() {
+		sum += x
+	}
+	if sum != 199*200/2 {
+		t.Error("sum of Values is", sum)
+	}
+}
+
+func TestAllReentrant(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	for i := 0; i < 200; i++ {
+		tc.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	for k, x := range tc.All() {
+		if len(k) <= 3 {
+			tc.Delete(k)
+			tc.Set("new"+k, x, DefaultExpiration)
+		}
+	}
+	for k := range tc.Keys() {
+		if _, found := tc.Get(k); !found {
+			t.Error(k, "was not found")
+		}
+	}
+}
+
+func TestAllConcurrent(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	for i := 0; i < 1000; i++ {
+		tc.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			tc.Set(strconv.Itoa(i+1000), i, DefaultExpiration)
+			tc.Delete(strconv.Itoa(i))
+		}
+	}()
+	for range tc.All() {
+	}
+	wg.Wait()
+}
+
+func TestShardedAll(t *testing.T) {
+	tc := NewSharded[int](DefaultExpiration, 0, 4)
+	for i, k := range shardedKeys {
+		tc.Set(k, i, DefaultExpiration)
+	}
+	seen := map[string]bool{}
+	for k, x := range tc.All() {
+		if shardedKeys[x] != k {
+			t.Error(k, "has the value", x)
+		}
+		seen[k] = true
+	}
+	if len(seen) != len(shardedKeys) {
+		t.Error("All visited", len(seen), "items")
+	}
+	n := 0
+	for range tc.Values() {
+		n++
+		break
+	}
+	for range tc.Keys() {
+		n++
+	}
+	if n != len(shardedKeys)+1 {
+		t.Error("Keys and Values visited", n, "items")
+	}
+}
+
+// withTimeout fails the test if f doesn't return within a few seconds, e.g.
+// because it deadlocked.
+func withTimeout(t *testing.T, f func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deadlock")
+	}
+}
+
+func TestRangeReentrant(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	for i := 0; i < 200; i++ {
+		tc.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	seen := map[string]bool{}
+	withTimeout(t, func() {
+		tc.Range(func(k string, x int) bool {
+			if seen[k] {
+				t.Error(k, "was visited twice")
+			}
+			seen[k] = true
+			tc.Delete(k)
+			tc.Set(k, x+1, DefaultExpiration)
+			tc.Set("new"+k, x, DefaultExpiration)
+			tc.Increment(k, 1)
+			return true
+		})
+	})
+	if len(seen) != 200 {
+		t.Error("Range visited", len(seen), "items")
+	}
+	for k := range seen {
+		if len(k) > 3 {
+			t.Error("Range visited", k, "which was stored by f")
+		}
+	}
+	if x, _ := tc.Get("7"); x != 9 {
+		t.Error("7 is not 9:", x)
+	}
+}
+
+func TestRangeUpdated(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	for i := 0; i < 200; i++ {
+		tc.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	n := 0
+	withTimeout(t, func() {
+		tc.Range(func(k string, x int) bool {
+			n++
+			// Change every item, including those not visited yet.
+			for i := 0; i < 200; i++ {
+				if i%2 == 0 {
+					tc.Increment(strconv.Itoa(i), 1)
+				} else {
+					tc.Set(strconv.Itoa(i), i, DefaultExpiration)
+				}
+			}
+			return true
+		})
+	})
+	if n != 200 {
+		t.Error("Range visited", n, "of 200 items")
+	}
+}
+
+func TestRangeFlush(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	for i := 0; i < 200; i++ {
+		tc.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	n := 0
+	withTimeout(t, func() {
+		tc.Range(func(k string, x int) bool {
+			n++
+			tc.Flush()
+			tc.Set(k, x, DefaultExpiration)
+			return true
+		})
+	})
+	if n > iterChunk {
+		t.Error("Range visited", n, "items after Flush")
+	}
+}
+
+func TestShardedRangeReentrant(t *testing.T) {
+	tc := NewSharded[int](DefaultExpiration, 0, 4)
+	for i, k := range shardedKeys {
+		tc.Set(k, i, DefaultExpiration)
+	}
+	n := 0
+	withTimeout(t, func() {
+		tc.Range(func(k string, x int) bool {
+			n++
+			tc.Delete(k)
+			tc.Set(k, x, DefaultExpiration)
+			return true
+		})
+	})
+	if n != len(shardedKeys) {
+		t.Error("Range visited", n, "items")
+	}
+}
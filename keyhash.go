@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/maphash"
+	"math"
+	"reflect"
+	"unsafe"
+)
+
+// keyPart is a part of a key that keyHasher hashes: a string, a floating
+// point number, an interface, or anything else that is compared bit by bit.
+type keyPart struct {
+	offset uintptr
+	size   uintptr
+	kind   reflect.Kind
+	typ    reflect.Type
+}
+
+// keyHasher hashes keys of any comparable type for the frequency sketch of
+// tinyLFU, so that equal keys get equal hashes, without allocating. It works
+// out once which parts of a K make up its value, i.e. its fields, but not the
+// padding between them, and then hashes those parts of each key in place.
+// Only keys containing interfaces are hashed by their printed form, which is
+// slower.
+//
+// A keyHasher is not safe for concurrent use.
+type keyHasher[K comparable] struct {
+	h     maphash.Hash
+	parts []keyPart
+	// key holds the key being hashed, so that its parts can be read without
+	// taking the address of the argument, which would move it to the heap.
+	key K
+}
+
+func newKeyHasher[K comparable]() *keyHasher[K] {
+	kh := &keyHasher[K]{}
+	kh.h.SetSeed(maphash.MakeSeed())
+	kh.parts = appendKeyParts(nil, reflect.TypeFor[K](), 0)
+	return kh
+}
+
+// appendKeyParts appends the parts of a value of type t at offset.
+func appendKeyParts(parts []keyPart, t reflect.Type, offset uintptr) []keyPart {
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			// Blank fields are not compared.
+			if f.Name != "_" {
+				parts = appendKeyParts(parts, f.Type, offset+f.Offset)
+			}
+		}
+		return parts
+	case reflect.Array:
+		for i := 0; i < t.Len(); i++ {
+			parts = appendKeyParts(parts, t.Elem(), offset+uintptr(i)*t.Elem().Size())
+		}
+		return parts
+	case reflect.Complex64:
+		f := reflect.TypeFor[float32]()
+		return appendKeyParts(appendKeyParts(parts, f, offset), f, offset+4)
+	case reflect.Complex128:
+		f := reflect.TypeFor[float64]()
+		return appendKeyParts(appendKeyParts(parts, f, offset), f, offset+8)
+	case reflect.String, reflect.Float32, reflect.Float64, reflect.Interface:
+		return append(parts, keyPart{offset, t.Size(), t.Kind(), t})
+	}
+	// Compared bit by bit, so adjacent parts can be hashed together.
+	if n := len(parts); n > 0 && parts[n-1].kind == reflect.Invalid && parts[n-1].offset+parts[n-1].size == offset {
+		parts[n-1].size += t.Size()
+		return parts
+	}
+	return append(parts, keyPart{offset, t.Size(), reflect.Invalid, nil})
+}
+
+// hash returns the hash of k.
+func (kh *keyHasher[K]) hash(k K) uint64 {
+	kh.key = k
+	base := unsafe.Pointer(&kh.key)
+	kh.h.Reset()
+	var buf [8]byte
+	for _, part := range kh.parts {
+		p := unsafe.Add(base, part.offset)
+		switch part.kind {
+		case reflect.String:
+			s := *(*string)(p)
+			// Include the length, so that e.g. {"ab", "c"} and {"a", "bc"}
+			// differ.
+			binary.LittleEndian.PutUint64(buf[:], uint64(len(s)))
+			kh.h.Write(buf[:])
+			kh.h.WriteString(s)
+		case reflect.Float32:
+			// +0 and -0 are equal.
+			f := *(*float32)(p)
+			if f == 0 {
+				f = 0
+			}
+			binary.LittleEndian.PutUint32(buf[:], math.Float32bits(f))
+			kh.h.Write(buf[:4])
+		case reflect.Float64:
+			f := *(*float64)(p)
+			if f == 0 {
+				f = 0
+			}
+			binary.LittleEndian.PutUint64(buf[:], math.Float64bits(f))
+			kh.h.Write(buf[:])
+		case reflect.Interface:
+			v := reflect.NewAt(part.typ, p).Elem().Interface()
+			fmt.Fprintf(&kh.h, "%T:%#v;", v, v)
+		default:
+			kh.h.Write(unsafe.Slice((*byte)(p), part.size))
+		}
+	}
+	var zero K
+	kh.key = zero
+	return kh.h.Sum64()
+}
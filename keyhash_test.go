@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"math"
+	"testing"
+)
+
+func TestKeyHasher(t *testing.T) {
+	type point struct {
+		x, y int
+		_    int
+		name string
+		f    float64
+	}
+	ph := newKeyHasher[point]()
+	if ph.hash(point{1, 2, 0, "a", 0}) != ph.hash(point{1, 2, 3, "a", math.Copysign(0, -1)}) {
+		t.Error("equal struct keys have different hashes")
+	}
+	for _, p := range []point{{2, 1, 0, "a", 0}, {1, 2, 0, "b", 0}, {1, 2, 0, "a", 1}} {
+		if ph.hash(point{1, 2, 0, "a", 0}) == ph.hash(p) {
+			t.Error("different struct keys have the same hash:", p)
+		}
+	}
+	sh := newKeyHasher[[2]string]()
+	if sh.hash([2]string{"ab", "c"}) == sh.hash([2]string{"a", "bc"}) {
+		t.Error("different array keys have the same hash")
+	}
+	ih := newKeyHasher[any]()
+	if ih.hash(1) != ih.hash(1) || ih.hash(1) == ih.hash("1") {
+		t.Error("interface keys are not hashed by value and type")
+	}
+	if n := testing.AllocsPerRun(100, func() {
+		ph.hash(point{1, 2, 0, "a", 0})
+	}); n != 0 {
+		t.Error("hashing a struct key allocates:", n)
+	}
+}
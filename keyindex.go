@@ -0,0 +1,110 @@
+package cache
+
+// keyIndex keeps the keys of a cache created using WithKeyIndex in order. It
+// is only changed while holding the cache's write lock, and read while
+// holding its read lock.
+type keyIndex[K comparable] interface {
+	// add records that k was stored. k may already be in the index.
+	add(k K)
+	// remove removes k from the index, if it is there.
+	remove(k K)
+	// clear removes all keys.
+	clear()
+}
+
+// skipListMaxLevel allows for about 4^24 keys before lookups get slower.
+const skipListMaxLevel = 24
+
+type skipNode struct {
+	key  string
+	next []*skipNode
+}
+
+// skipList is the keyIndex of caches with string keys: a skip list, in which
+// each node is linked to the next one, every fourth node or so also to the
+// fourth one after it, and so on, so keys can be found in O(log n).
+type skipList struct {
+	head  skipNode
+	level int
+	rnd   uint64
+}
+
+func newSkipList() *skipList {
+	return &skipList{
+		head:  skipNode{next: make([]*skipNode, skipListMaxLevel)},
+		level: 1,
+		rnd:   0x9e3779b97f4a7c15,
+	}
+}
+
+// randomLevel returns the number of lists a new node joins: one with
+// probability 3/4, two with probability 3/16, and so on.
+func (l *skipList) randomLevel() int {
+	// xorshift64
+	l.rnd ^= l.rnd << 13
+	l.rnd ^= l.rnd >> 7
+	l.rnd ^= l.rnd << 17
+	level := 1
+	for r := l.rnd; level < skipListMaxLevel && r&3 == 0; r >>= 2 {
+		level++
+	}
+	return level
+}
+
+// path returns, for each level, the last node with a key less than k.
+func (l *skipList) path(k string) [skipListMaxLevel]*skipNode {
+	var path [skipListMaxLevel]*skipNode
+	n := &l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for n.next[i] != nil && n.next[i].key < k {
+			n = n.next[i]
+		}
+		path[i] = n
+	}
+	return path
+}
+
+func (l *skipList) add(k string) {
+	path := l.path(k)
+	if n := path[0].next[0]; n != nil && n.key == k {
+		return
+	}
+	level := l.randomLevel()
+	for ; l.level < level; l.level++ {
+		path[l.level] = &l.head
+	}
+	n := &skipNode{key: k, next: make([]*skipNode, level)}
+	for i := 0; i < level; i++ {
+		n.next[i] = path[i].next[i]
+		path[i].next[i] = n
+	}
+}
+
+func (l *skipList) remove(k string) {
+	path := l.path(k)
+	n := path[0].next[0]
+	if n == nil || n.key != k {
+		return
+	}
+	for i := range n.next {
+		path[i].next[i] = n.next[i]
+	}
+	for l.level > 1 && l.head.next[l.level-1] == nil {
+		l.level--
+	}
+}
+
+func (l *skipList) clear() {
+	clear(l.head.next)
+	l.level = 1
+}
+
+// seek returns the node with the smallest key that is at least k, or, if
+// after is true, greater than k. It returns nil if there is none.
+func (l *skipList) seek(k string, after bool) *skipNode {
+	n := l.path(k)[0].next[0]
+	if after && n != nil && n.key == k {
+		n = n.next[0]
+	}
+	return n
+}
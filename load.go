@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// loadCall is an in-flight or completed call to a loader.
+type loadCall[T any] struct {
+	done chan struct{}
+	val  T
+	err  error
+}
+
+// loadGroup deduplicates concurrent loads of the same key.
+type loadGroup[K comparable, T any] struct {
+	mu    sync.Mutex
+	calls map[K]*loadCall[T]
+}
+
+// start returns the in-flight call for k, if there is one. Otherwise it
+// registers a new call and reports that the caller must run it.
+func (g *loadGroup[K, T]) start(k K) (*loadCall[T], bool) {
+	g.mu.Lock()
+	if cl, found := g.calls[k]; found {
+		g.mu.Unlock()
+		return cl, false
+	}
+	if g.calls == nil {
+		g.calls = map[K]*loadCall[T]{}
+	}
+	cl := &loadCall[T]{done: make(chan struct{})}
+	g.calls[k] = cl
+	g.mu.Unlock()
+	return cl, true
+}
+
+// run calls fn, stores its result in cl and releases everyone waiting for it.
+func (g *loadGroup[K, T]) run(k K, cl *loadCall[T], fn func() (T, error)) {
+	defer func() {
+		if x := recover(); x != nil {
+			cl.err = fmt.Errorf("loader for %v panicked: %v", k, x)
+			g.finish(k, cl)
+			panic(x)
+		}
+	}()
+	cl.val, cl.err = fn()
+	g.finish(k, cl)
+}
+
+func (g *loadGroup[K, T]) finish(k K, cl *loadCall[T]) {
+	g.mu.Lock()
+	delete(g.calls, k)
+	g.mu.Unlock()
+	close(cl.done)
+}
+
+// GetOrLoad Get an item from the cache, or, if it isn't found, call loader to
+// produce it and Set it using the returned duration (which, like for Set, may
+// be DefaultExpiration or NoExpiration.) If loader returns an error, nothing
+// is stored and the error is returned.
+//
+// Only one loader runs per key at a time: callers that miss the same key while
+// it is being loaded wait for that loader and share its result or error,
+// rather than calling their own. The loader is passed the ctx of the caller
+// that started it. Waiting callers give up and return ctx.Err() when their own
+// ctx is done.
+func (c *cache[K, T]) GetOrLoad(ctx context.Context, k K, loader func(ctx context.Context) (T, time.Duration, error)) (T, error) {
+	if x, found := c.Get(k); found {
+		return x, nil
+	}
+	cl, leader := c.loads.start(k)
+	if leader {
+		c.loads.run(k, cl, func() (T, error) {
+			// Another loader may have stored k between the Get above and
+			// the start of this call.
+			c.mu.RLock()
+			item, found := c.items[k]
+			// Sliding expiration changes item.Expiration under the write
+			// lock, so check it before unlocking.
+			live := found && !c.expired(item)
+			var x T
+			if live {
+				x = item.Object
+			}
+			c.mu.RUnlock()
+			if live {
+				return x, nil
+			}
+			x, d, err := loader(ctx)
+			c.stats.countLoad(err)
+			if err != nil {
+				return x, err
+			}
+			c.Set(k, x, d)
+			return x, nil
+		})
+		return cl.val, cl.err
+	}
+	select {
+	case <-cl.done:
+		return cl.val, cl.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
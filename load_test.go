@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrLoad(t *testing.T) {
+	tc := New[string](DefaultExpiration, 0)
+	tc.Set("a", "cached", DefaultExpiration)
+	x, err := tc.GetOrLoad(context.Background(), "a", func(ctx context.Context) (string, time.Duration, error) {
+		t.Error("loader was called for a cached item")
+		return "", DefaultExpiration, nil
+	})
+	if err != nil || x != "cached" {
+		t.Error("a is not cached:", x, err)
+	}
+
+	x, err = tc.GetOrLoad(context.Background(), "b", func(ctx context.Context) (string, time.Duration, error) {
+		return "loaded", time.Hour, nil
+	})
+	if err != nil || x != "loaded" {
+		t.Error("b is not loaded:", x, err)
+	}
+	y, expiration, found := tc.GetWithExpiration("b")
+	if !found || y.(string) != "loaded" {
+		t.Error("b was not stored")
+	}
+	if expiration.Before(time.Now().Add(59 * time.Minute)) {
+		t.Error("b does not expire in an hour:", expiration)
+	}
+}
+
+func TestGetOrLoadError(t *testing.T) {
+	tc := New[string](DefaultExpiration, 0)
+	loadErr := errors.New("database is down")
+	_, err := tc.GetOrLoad(context.Background(), "a", func(ctx context.Context) (string, time.Duration, error) {
+		return "", DefaultExpiration, loadErr
+	})
+	if err != loadErr {
+		t.Error("error is not the loader's error:", err)
+	}
+	if _, found := tc.Get("a"); found {
+		t.Error("a was stored even though loading it failed")
+	}
+}
+
+func TestGetOrLoadConcurrent(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	var calls int32
+	loader := func(ctx context.Context) (int, time.Duration, error) {
+		atomic.AddInt32(&calls, 1)
+		<-time.After(50 * time.Millisecond)
+		return 42, DefaultExpiration, nil
+	}
+	wg := new(sync.WaitGroup)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			x, err := tc.GetOrLoad(context.Background(), "a", loader)
+			if err != nil || x != 42 {
+				t.Error("a is not 42:", x, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Error("loader was called more than once:", n)
+	}
+}
+
+func TestGetOrLoadWaiterContext(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	started := make(chan bool)
+	release := make(chan bool)
+	go func() {
+		_, _ = tc.GetOrLoad(context.Background(), "a", func(ctx context.Context) (int, time.Duration, error) {
+			close(started)
+			<-release
+			return 1, DefaultExpiration, nil
+		})
+	}()
+	<-started
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := tc.GetOrLoad(ctx, "a", func(ctx context.Context) (int, time.Duration, error) {
+		t.Error("a second loader was called while the first was running")
+		return 2, DefaultExpiration, nil
+	})
+	if err != context.Canceled {
+		t.Error("error is not context.Canceled:", err)
+	}
+	close(release)
+}
+
+func TestShardedCacheGetOrLoad(t *testing.T) {
+	tc := NewSharded[int](DefaultExpiration, 0, 4)
+	x, err := tc.GetOrLoad(context.Background(), "a", func(ctx context.Context) (int, time.Duration, error) {
+		return 1, DefaultExpiration, nil
+	})
+	if err != nil || x != 1 {
+		t.Error("a is not 1:", x, err)
+	}
+	if x, _ = tc.Get("a"); x != 1 {
+		t.Error("a was not stored")
+	}
+}
+
+func BenchmarkGetOrLoadHit(b *testing.B) {
+	b.StopTimer()
+	tc := New[string](DefaultExpiration, 0)
+	tc.Set("foo", "bar", DefaultExpiration)
+	ctx := context.Background()
+	loader := func(ctx context.Context) (string, time.Duration, error) {
+		return "bar", DefaultExpiration, nil
+	}
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = tc.GetOrLoad(ctx, "foo", loader)
+	}
+}
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lru is the LRU policy: it evicts the least recently used key. It has its own
+// mutex so that Get can promote keys while only holding the cache's read lock.
+type lru[K comparable] struct {
+	mu    sync.Mutex
+	ll    *list.List
+	elems map[K]*list.Element
+}
+
+func newLRU[K comparable]() *lru[K] {
+	return &lru[K]{
+		ll:    list.New(),
+		elems: map[K]*list.Element{},
+	}
+}
+
+// add records k as the most recently used key.
+func (l *lru[K]) add(k K) {
+	l.mu.Lock()
+	if e, found := l.elems[k]; found {
+		l.ll.MoveToFront(e)
+	} else {
+		l.elems[k] = l.ll.PushFront(k)
+	}
+	l.mu.Unlock()
+}
+
+// access marks k as used, if it is tracked.
+func (l *lru[K]) access(k K) {
+	l.mu.Lock()
+	if e, found := l.elems[k]; found {
+		l.ll.MoveToFront(e)
+	}
+	l.mu.Unlock()
+}
+
+// remove stops tracking k.
+func (l *lru[K]) remove(k K) {
+	l.mu.Lock()
+	if e, found := l.elems[k]; found {
+		l.ll.Remove(e)
+		delete(l.elems, k)
+	}
+	l.mu.Unlock()
+}
+
+// evict stops tracking the least recently used key and returns it.
+func (l *lru[K]) evict() (K, bool) {
+	l.mu.Lock()
+	e := l.ll.Back()
+	if e == nil {
+		l.mu.Unlock()
+		var zero K
+		return zero, false
+	}
+	k := l.ll.Remove(e).(K)
+	delete(l.elems, k)
+	l.mu.Unlock()
+	return k, true
+}
+
+func (l *lru[K]) clear() {
+	l.mu.Lock()
+	l.ll.Init()
+	l.elems = map[K]*list.Element{}
+	l.mu.Unlock()
+}
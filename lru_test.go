@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestMaxItems(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0, WithMaxItems(3))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("b", 2, DefaultExpiration)
+	tc.Set("c", 3, DefaultExpiration)
+	tc.Set("d", 4, DefaultExpiration)
+	if n := tc.ItemCount(); n != 3 {
+		t.Errorf("Item count is not 3: %d", n)
+	}
+	if _, found := tc.Get("a"); found {
+		t.Error("a was found, but it should have been evicted")
+	}
+	for _, k := range []string{"b", "c", "d"} {
+		if _, found := tc.Get(k); !found {
+			t.Error(k, "was not found")
+		}
+	}
+}
+
+func TestMaxItemsGetPromotes(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0, WithMaxItems(2))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("b", 2, DefaultExpiration)
+	tc.Get("a")
+	tc.Set("c", 3, DefaultExpiration)
+	if _, found := tc.Get("a"); !found {
+		t.Error("a was not found, but it was used more recently than b")
+	}
+	if _, found := tc.Get("b"); found {
+		t.Error("b was found, but it should have been evicted")
+	}
+}
+
+func TestMaxItemsOverwrite(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0, WithMaxItems(2))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("b", 2, DefaultExpiration)
+	tc.Set("a", 3, DefaultExpiration)
+	tc.Set("c", 4, DefaultExpiration)
+	if x, found := tc.Get("a"); !found || x != 3 {
+		t.Error("a was not 3:", x)
+	}
+	if _, found := tc.Get("b"); found {
+		t.Error("b was found, but it should have been evicted")
+	}
+}
+
+func TestMaxItemsOnEvicted(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0, WithMaxItems(1))
+	var evicted []string
+	tc.OnEvicted(func(k string, v int) {
+		evicted = append(evicted, k+"="+strconv.Itoa(v))
+	})
+	tc.Set("a", 1, DefaultExpiration)
+	if err := tc.Add("b", 2, DefaultExpiration); err != nil {
+		t.Fatal("Couldn't add b:", err)
+	}
+	tc.Delete("b")
+	if len(evicted) != 2 || evicted[0] != "a=1" || evicted[1] != "b=2" {
+		t.Error("evicted items are not [a=1 b=2]:", evicted)
+	}
+}
+
+func TestMaxItemsNewFrom(t *testing.T) {
+	m := map[string]*Item[int]{
+		"a": {Object: 1},
+		"b": {Object: 2},
+		"c": {Object: 3},
+	}
+	tc := NewFrom[int](DefaultExpiration, 0, m, WithMaxItems(2))
+	if n := tc.ItemCount(); n != 2 {
+		t.Errorf("Item count is not 2: %d", n)
+	}
+	tc.Set("d", 4, DefaultExpiration)
+	if n := tc.ItemCount(); n != 2 {
+		t.Errorf("Item count is not 2: %d", n)
+	}
+	tc.Flush()
+	tc.Set("e", 5, DefaultExpiration)
+	tc.Set("f", 6, DefaultExpiration)
+	if n := tc.ItemCount(); n != 2 {
+		t.Errorf("Item count after Flush is not 2: %d", n)
+	}
+}
+
+func BenchmarkCacheSetMaxItems(b *testing.B) {
+	b.StopTimer()
+	tc := New[string](DefaultExpiration, 0, WithMaxItems(1000))
+	keys := make([]string, 2000)
+	for i := range keys {
+		keys[i] = "foo" + strconv.Itoa(i)
+	}
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		tc.Set(keys[i%len(keys)], "bar", DefaultExpiration)
+	}
+}
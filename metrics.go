@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StatsSource is implemented by Cache, KeyedCache and ShardedCache, and is
+// what a Registry, and so a MetricsHandler, reads its metrics from.
+type StatsSource interface {
+	Stats() Stats
+	ItemCount() int
+}
+
+// MetricsHandler is an http.Handler that serves the statistics of the caches
+// in a Registry in the Prometheus text exposition format. Each metric carries
+// a "cache" label with the name the cache was registered under.
+type MetricsHandler struct {
+	registry *Registry
+}
+
+// NewMetricsHandler returns a MetricsHandler for the caches in r, or, if r is
+// nil, in DefaultRegistry, which includes all caches created using WithName.
+func NewMetricsHandler(r *Registry) *MetricsHandler {
+	if r == nil {
+		r = DefaultRegistry
+	}
+	return &MetricsHandler{registry: r}
+}
+
+// ServeHTTP Writes the metrics of all registered caches.
+func (h *MetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = h.WriteMetrics(w)
+}
+
+type cacheMetrics struct {
+	name  string
+	items int
+	stats Stats
+}
+
+// WriteMetrics Writes the metrics of all registered caches to w, ordered by
+// cache name.
+func (h *MetricsHandler) WriteMetrics(w io.Writer) error {
+	var ms []cacheMetrics
+	h.registry.each(func(name string, c StatsSource) {
+		ms = append(ms, cacheMetrics{name, c.ItemCount(), c.Stats()})
+	})
+	sort.Slice(ms, func(i, j int) bool { return ms[i].name < ms[j].name })
+
+	bw := bufio.NewWriter(w)
+	family := func(name, typ, help string, value func(m cacheMetrics, label string) string) {
+		bw.WriteString("# HELP " + name + " " + help + "\n")
+		bw.WriteString("# TYPE " + name + " " + typ + "\n")
+		for _, m := range ms {
+			label := `cache="` + escapeLabel(m.name) + `"`
+			bw.WriteString(value(m, label))
+		}
+	}
+	sample := func(name, label string, v uint64) string {
+		return name + "{" + label + "} " + strconv.FormatUint(v, 10) + "\n"
+	}
+	counter := func(name, help string, v func(Stats) uint64) {
+		family(name, "counter", help, func(m cacheMetrics, label string) string {
+			return sample(name, label, v(m.stats))
+		})
+	}
+
+	family("go_cache_items", "gauge", "Number of items in the cache, including expired items not yet deleted.",
+		func(m cacheMetrics, label string) string {
+			return sample("go_cache_items", label, uint64(m.items))
+		})
+	counter("go_cache_hits_total", "Number of reads that found an item.",
+		func(s Stats) uint64 { return s.Hits })
+	counter("go_cache_misses_total", "Number of reads that found no item.",
+		func(s Stats) uint64 { return s.Misses })
+	family("go_cache_hit_ratio", "gauge", "Ratio of hits to reads.",
+		func(m cacheMetrics, label string) string {
+			return "go_cache_hit_ratio{" + label + "} " + strconv.FormatFloat(m.stats.HitRatio(), 'g', -1, 64) + "\n"
+		})
+	counter("go_cache_sets_total", "Number of items stored.",
+		func(s Stats) uint64 { return s.Sets })
+	family("go_cache_evictions_total", "counter", "Number of items removed from the cache, by reason.",
+		func(m cacheMetrics, label string) string {
+			var b strings.Builder
+			for _, r := range []EvictionReason{Expired, Deleted, Replaced, Flushed, CapacityEvicted, Invalidated} {
+				b.WriteString(sample("go_cache_evictions_total", label+`,reason="`+r.String()+`"`, m.stats.Evicted(r)))
+			}
+			return b.String()
+		})
+	family("go_cache_loads_total", "counter", "Number of loader calls, by result.",
+		func(m cacheMetrics, label string) string {
+			return sample("go_cache_loads_total", label+`,result="success"`, m.stats.LoadSuccesses) +
+				sample("go_cache_loads_total", label+`,result="failure"`, m.stats.LoadFailures)
+		})
+	family("go_cache_janitor_run_duration_seconds", "summary", "Time taken by runs of the janitor.",
+		func(m cacheMetrics, label string) string {
+			return "go_cache_janitor_run_duration_seconds_sum{" + label + "} " +
+				strconv.FormatFloat(m.stats.JanitorDuration.Seconds(), 'g', -1, 64) + "\n" +
+				sample("go_cache_janitor_run_duration_seconds_count", label, m.stats.JanitorRuns)
+		})
+	return bw.Flush()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tc := New[int](DefaultExpiration, time.Second, WithClock(clock), WithMaxItems(2))
+	defer tc.Close()
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("b", 2, DefaultExpiration)
+	tc.Set("c", 3, DefaultExpiration)
+	tc.Get("c")
+	tc.Get("a")
+	clock.Advance(time.Second)
+	sc := NewSharded[string](DefaultExpiration, 0, 2)
+	sc.Set("x", "y", DefaultExpiration)
+
+	r := NewRegistry()
+	r.Register("sessions", tc)
+	r.Register(`weird "name"`, sc)
+	h := NewMetricsHandler(r)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Error("content type is", ct)
+	}
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE go_cache_items gauge",
+		`go_cache_items{cache="sessions"} 2`,
+		`go_cache_items{cache="weird \"name\""} 1`,
+		`go_cache_hits_total{cache="sessions"} 1`,
+		`go_cache_misses_total{cache="sessions"} 1`,
+		`go_cache_hit_ratio{cache="sessions"} 0.5`,
+		`go_cache_sets_total{cache="sessions"} 3`,
+		`go_cache_evictions_total{cache="sessions",reason="capacity"} 1`,
+		`go_cache_evictions_total{cache="sessions",reason="expired"} 0`,
+		`go_cache_loads_total{cache="sessions",result="failure"} 0`,
+		"# TYPE go_cache_janitor_run_duration_seconds summary",
+		`go_cache_janitor_run_duration_seconds_count{cache="sessions"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("metrics do not contain %q:\n%s", line, body)
+		}
+	}
+	if strings.Index(body, `cache="sessions"`) > strings.Index(body, `cache="weird`) {
+		t.Error("caches are not ordered by name")
+	}
+
+	r.Unregister("sessions")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if strings.Contains(rec.Body.String(), "sessions") {
+		t.Error("unregistered cache is still exported")
+	}
+}
+
+func TestMetricsHandlerDefaultRegistry(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0, WithName("test-metrics"))
+	defer tc.Close()
+	tc.Set("a", 1, DefaultExpiration)
+	rec := httptest.NewRecorder()
+	NewMetricsHandler(nil).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), `go_cache_items{cache="test-metrics"} 1`+"\n") {
+		t.Errorf("metrics do not contain the cache created using WithName:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,153 @@
+package cache
+
+import "time"
+
+// GetMulti Get the items with the given keys from the cache, locking it only
+// once. It returns a map of the keys that were found to their values, and
+// otherwise behaves like calling Get for each key.
+func (c *cache[K, T]) GetMulti(keys []K) map[K]T {
+	m := make(map[K]T, len(keys))
+	var refresh []K
+	var sliding []K
+	var slidingItems []*Item[T]
+	var hits, misses uint64
+	c.mu.RLock()
+	rp := c.refresh
+	now := c.now()
+	for _, k := range keys {
+		item, found := c.items[k]
+		if !found {
+			misses++
+			continue
+		}
+		if item.Expiration > 0 {
+			if now > item.Expiration && !rp.serveStale(item, now) {
+				misses++
+				continue
+			}
+			if rp.due(item, now) {
+				refresh = append(refresh, k)
+			}
+		}
+		if c.policy != nil {
+			c.policy.access(k)
+		}
+		if item.Sliding > 0 {
+			sliding = append(sliding, k)
+			slidingItems = append(slidingItems, item)
+		}
+		m[k] = item.Object
+		hits++
+	}
+	c.mu.RUnlock()
+	c.stats.hits.Add(hits)
+	c.stats.misses.Add(misses)
+	for _, k := range refresh {
+		c.startRefresh(k, rp)
+	}
+	if len(sliding) > 0 {
+		c.slideMulti(sliding, slidingItems)
+	}
+	return m
+}
+
+// slideMulti renews the sliding expirations of items, which were read from
+// keys, like slide does for a single item.
+func (c *cache[K, T]) slideMulti(keys []K, items []*Item[T]) {
+	now := c.clock.Now()
+	c.mu.Lock()
+	for i, k := range keys {
+		item := items[i]
+		if c.items[k] == item {
+			c.renew(k, item, now.Add(item.Sliding).UnixNano())
+		}
+	}
+	c.mu.Unlock()
+}
+
+// SetMulti Add the given items to the cache, replacing any existing items,
+// locking it only once. The duration is interpreted as for Set, and applies
+// to all of the items.
+func (c *cache[K, T]) SetMulti(items map[K]T, d time.Duration) {
+	var evictedItems []evictedItem[K, T]
+	c.mu.Lock()
+	for k, x := range items {
+		evictedItems = append(evictedItems, c.set(k, x, d)...)
+	}
+	c.mu.Unlock()
+	c.evicted(evictedItems)
+}
+
+// DeleteMulti Delete the items with the given keys from the cache, locking it
+// only once. Keys that are not in the cache are ignored. The eviction
+// functions are called after the cache is unlocked.
+func (c *cache[K, T]) DeleteMulti(keys []K) {
+	var evictedItems []evictedItem[K, T]
+	var deletes uint64
+	c.mu.Lock()
+	for _, k := range keys {
+		v, found := c.delete(k)
+		if found {
+			deletes++
+			if c.observesEvictions() {
+				evictedItems = append(evictedItems, evictedItem[K, T]{k, v, Deleted})
+			}
+		}
+	}
+	c.mu.Unlock()
+	c.stats.deletes.Add(deletes)
+	c.evicted(evictedItems)
+}
+
+// GetMulti Get the items with the given keys, locking each shard only once.
+// See Cache.GetMulti.
+func (sc *shardedCache[T]) GetMulti(keys []string) map[string]T {
+	m := make(map[string]T, len(keys))
+	for i, ks := range sc.shardKeys(keys) {
+		if len(ks) == 0 {
+			continue
+		}
+		for k, x := range sc.cs[i].GetMulti(ks) {
+			m[k] = x
+		}
+	}
+	return m
+}
+
+// SetMulti Add the given items, locking each shard only once. See
+// Cache.SetMulti.
+func (sc *shardedCache[T]) SetMulti(items map[string]T, d time.Duration) {
+	shards := make([]map[string]T, len(sc.cs))
+	for k, x := range items {
+		i := djb33(sc.seed, k) % sc.m
+		if shards[i] == nil {
+			shards[i] = map[string]T{}
+		}
+		shards[i][k] = x
+	}
+	for i, m := range shards {
+		if m != nil {
+			sc.cs[i].SetMulti(m, d)
+		}
+	}
+}
+
+// DeleteMulti Delete the items with the given keys, locking each shard only
+// once. See Cache.DeleteMulti.
+func (sc *shardedCache[T]) DeleteMulti(keys []string) {
+	for i, ks := range sc.shardKeys(keys) {
+		if len(ks) > 0 {
+			sc.cs[i].DeleteMulti(ks)
+		}
+	}
+}
+
+// shardKeys splits keys by the shard they belong to.
+func (sc *shardedCache[T]) shardKeys(keys []string) [][]string {
+	shards := make([][]string, len(sc.cs))
+	for _, k := range keys {
+		i := djb33(sc.seed, k) % sc.m
+		shards[i] = append(shards[i], k)
+	}
+	return shards
+}
@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGetMulti(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tc := New[int](DefaultExpiration, 0, WithClock(clock))
+	tc.SetMulti(map[string]int{"a": 1, "b": 2, "c": 3}, DefaultExpiration)
+	tc.Set("d", 4, time.Second)
+	clock.Advance(2 * time.Second)
+	m := tc.GetMulti([]string{"a", "c", "d", "e"})
+	if len(m) != 2 || m["a"] != 1 || m["c"] != 3 {
+		t.Error("GetMulti returned", m)
+	}
+	if s := tc.Stats(); s.Hits != 2 || s.Misses != 2 || s.Sets != 4 {
+		t.Errorf("stats are %+v", s)
+	}
+}
+
+func TestGetMultiSliding(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tc := New[int](DefaultExpiration, 0, WithClock(clock))
+	tc.SetSliding("a", 1, time.Second)
+	tc.SetSliding("b", 2, time.Second)
+	for i := 0; i < 4; i++ {
+		clock.Advance(time.Second / 2)
+		tc.GetMulti([]string{"a"})
+	}
+	if m := tc.GetMulti([]string{"a", "b"}); len(m) != 1 || m["a"] != 1 {
+		t.Error("sliding expirations were not renewed by GetMulti:", m)
+	}
+}
+
+func TestSetMultiMaxItems(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0, WithMaxItems(2))
+	var evicted []string
+	tc.OnEvicted(func(k string, x int) {
+		evicted = append(evicted, k)
+	})
+	tc.Set("a", 1, DefaultExpiration)
+	tc.SetMulti(map[string]int{"b": 2, "c": 3}, DefaultExpiration)
+	if n := tc.ItemCount(); n != 2 {
+		t.Error("ItemCount is not 2:", n)
+	}
+	if len(evicted) != 1 || evicted[0] != "a" {
+		t.Error("evicted items are", evicted)
+	}
+}
+
+func TestDeleteMulti(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	tc.SetMulti(map[string]int{"a": 1, "b": 2, "c": 3}, DefaultExpiration)
+	var evicted []string
+	tc.OnEvictedWithReason(func(k string, item Item[int], r EvictionReason) {
+		if r != Deleted {
+			t.Error("reason is not Deleted:", r)
+		}
+		// The cache must not be locked.
+		tc.Get(k)
+		evicted = append(evicted, k)
+	})
+	tc.DeleteMulti([]string{"a", "c", "d"})
+	sort.Strings(evicted)
+	if len(evicted) != 2 || evicted[0] != "a" || evicted[1] != "c" {
+		t.Error("evicted items are", evicted)
+	}
+	if n := tc.ItemCount(); n != 1 {
+		t.Error("ItemCount is not 1:", n)
+	}
+	if s := tc.Stats(); s.Deletes != 2 {
+		t.Error("deletes are not 2:", s.Deletes)
+	}
+}
+
+func TestShardedMulti(t *testing.T) {
+	tc := NewSharded[int](DefaultExpiration, 0, 4)
+	items := map[string]int{}
+	for i, k := range shardedKeys {
+		items[k] = i
+	}
+	tc.SetMulti(items, DefaultExpiration)
+	m := tc.GetMulti(append(shardedKeys, "missing"))
+	if len(m) != len(shardedKeys) {
+		t.Error("GetMulti returned", len(m), "items")
+	}
+	for i, k := range shardedKeys {
+		if m[k] != i {
+			t.Error(k, "is not", i, ":", m[k])
+		}
+	}
+	tc.DeleteMulti(shardedKeys[1:])
+	if n := tc.ItemCount(); n != 1 {
+		t.Error("ItemCount is not 1:", n)
+	}
+}
+
+func BenchmarkGetMulti(b *testing.B) {
+	tc := New[int](DefaultExpiration, 0)
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = "key" + strconv.Itoa(i)
+		tc.Set(keys[i], i, DefaultExpiration)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tc.GetMulti(keys)
+	}
+}
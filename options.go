@@ -0,0 +1,118 @@
+package cache
+
+import "context"
+
+// Option configures optional behaviour of a cache created with New or NewFrom.
+type Option func(*options)
+
+type options struct {
+	maxItems int
+	maxCost  int64
+	costFunc any // func(T) int64
+	policy   EvictionPolicy
+	sliding  bool
+	clock    Clock
+	ctx      context.Context
+	name     string
+	keyIndex bool
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.clock == nil {
+		o.clock = systemClock{}
+	}
+	return o
+}
+
+// WithMaxItems limits the cache to n items. When a Set, Add or Replace would
+// grow the cache beyond n items, the least recently used items are evicted
+// (and passed to the function given to OnEvicted, if any.) Getting an item
+// counts as using it. If n is less than one, the cache is unbounded. See
+// WithEvictionPolicy for other ways of choosing the items to evict.
+func WithMaxItems(n int) Option {
+	return func(o *options) {
+		o.maxItems = n
+	}
+}
+
+// WithEvictionPolicy selects how a cache bounded by WithMaxItems or
+// WithMaxCost chooses the items to evict. The default is LRU. With TinyLFU,
+// a Set may evict the item it stored if it is used less often than the items
+// already in the cache.
+func WithEvictionPolicy(p EvictionPolicy) Option {
+	return func(o *options) {
+		o.policy = p
+	}
+}
+
+// WithMaxCost limits the total cost of the items in the cache to n. When a
+// Set, Add or Replace would take the cache beyond n, the least recently used
+// items are evicted (and passed to the function given to OnEvicted, if any.)
+// An item that costs more than n on its own is evicted right away. The cost
+// of an item is given to SetWithCost, or else computed when the item is
+// stored: by the function given to WithCostFunc, by the item's Cost method
+// if it implements Sizer, or else it is one. If n is less than one, the cost
+// is unbounded. WithMaxCost can be combined with WithMaxItems.
+func WithMaxCost(n int64) Option {
+	return func(o *options) {
+		o.maxCost = n
+	}
+}
+
+// WithCostFunc makes a cache bounded by WithMaxCost use f to compute the cost
+// of the items stored in it, instead of Sizer. T must be the item type of the
+// cache, or New panics.
+func WithCostFunc[T any](f func(T) int64) Option {
+	return func(o *options) {
+		o.costFunc = f
+	}
+}
+
+// WithSlidingExpiration makes every item stored by Set, Add or Replace expire
+// after it hasn't been read for its expiration duration, rather than after the
+// duration since it was stored, as if it had been stored using SetSliding.
+func WithSlidingExpiration() Option {
+	return func(o *options) {
+		o.sliding = true
+	}
+}
+
+// WithClock makes the cache use the given clock, e.g. a FakeClock, instead of
+// the system time, both to expire items and to run the janitor.
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithContext makes the cache close itself (see Close) when ctx is done.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithName adds the cache to DefaultRegistry under the given name, which
+// publishes its statistics, size and configuration under expvar, and serves
+// them from NewMetricsHandler(nil). The cache is removed from the registry
+// again when it is closed.
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// WithKeyIndex makes the cache keep its keys in order, so that ScanPrefix,
+// DeletePrefix, RangeFrom, Scan and DeleteMatching take O(log n) time plus
+// the time to visit the matching items, rather than scanning all keys. Storing and deleting items
+// takes O(log n) time instead of O(1). It can only be used with string keys;
+// otherwise New panics.
+func WithKeyIndex() Option {
+	return func(o *options) {
+		o.keyIndex = true
+	}
+}
@@ -0,0 +1,43 @@
+package cache
+
+// EvictionPolicy selects how a bounded cache chooses the items to evict. See
+// WithEvictionPolicy.
+type EvictionPolicy int
+
+const (
+	// LRU evicts the least recently used item.
+	LRU EvictionPolicy = iota
+	// TinyLFU is Window-TinyLFU: new items enter a small LRU window, and
+	// items leaving the window are only admitted to the main, segmented LRU
+	// if they are used more often than the item they would replace. Use
+	// frequencies are estimated by a count-min sketch that is halved
+	// periodically, so old popularity fades. It keeps frequently used
+	// items in the cache through scans that would flush an LRU cache.
+	TinyLFU
+)
+
+// policy tracks the keys of a bounded cache and decides which one to evict
+// next. Implementations have their own mutex so that Get can call access
+// while only holding the cache's read lock.
+type policy[K comparable] interface {
+	// add records that k was stored.
+	add(k K)
+	// access records that k was read, if it is tracked.
+	access(k K)
+	// remove stops tracking k.
+	remove(k K)
+	// evict stops tracking the key that should be evicted next and
+	// returns it.
+	evict() (K, bool)
+	// clear stops tracking all keys.
+	clear()
+}
+
+// newPolicy returns the implementation of p for a cache of about n items, or
+// of unknown size if n is less than one.
+func newPolicy[K comparable](p EvictionPolicy, n int) policy[K] {
+	if p == TinyLFU {
+		return newTinyLFU[K](n)
+	}
+	return newLRU[K]()
+}
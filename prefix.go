@@ -0,0 +1,245 @@
+package cache
+
+import (
+	"iter"
+	"slices"
+)
+
+// keyRange is the range of keys from lo up to, but not including, hi. If hi
+// is empty, the range has no upper bound.
+type keyRange struct {
+	lo, hi string
+}
+
+// prefixRange returns the range of keys that start with prefix.
+func prefixRange(prefix string) keyRange {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] < 0xff {
+			hi := []byte(prefix[:i+1])
+			hi[i]++
+			return keyRange{prefix, string(hi)}
+		}
+	}
+	return keyRange{prefix, ""}
+}
+
+func (r keyRange) contains(k string) bool {
+	return k >= r.lo && (r.hi == "" || k < r.hi)
+}
+
+// orderedKeys returns, in order, up to n keys in r, or, if resume is true, in
+// r and after the key after. It also reports whether there may be more. It
+// must be called while holding c.mu. Without a key index it scans all keys,
+// but only keeps n of them.
+func orderedKeys[T any](c *cache[string, T], r keyRange, after string, resume bool, n int) ([]string, bool) {
+	var keys []string
+	if idx, ok := c.index.(*skipList); ok {
+		node := idx.seek(r.lo, false)
+		if resume {
+			node = idx.seek(after, true)
+		}
+		for ; node != nil && r.contains(node.key); node = node.next[0] {
+			if len(keys) == n {
+				return keys, true
+			}
+			keys = append(keys, node.key)
+		}
+		return keys, false
+	}
+	if n >= len(c.items) {
+		for k := range c.items {
+			if r.contains(k) && (!resume || k > after) {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+		return keys, false
+	}
+	more := false
+	for k := range c.items {
+		if !r.contains(k) || (resume && k <= after) {
+			continue
+		}
+		if len(keys) == n && k > keys[n-1] {
+			more = true
+			continue
+		}
+		i, _ := slices.BinarySearch(keys, k)
+		keys = slices.Insert(keys, i, k)
+		if len(keys) > n {
+			keys, more = keys[:n], true
+		}
+	}
+	return keys, more
+}
+
+// chunkSize returns how many keys rangeItems and deleteRange handle at a
+// time: a few with a key index, and all of them without one, since each
+// chunk takes a scan of all keys then. It must be called while holding c.mu.
+func chunkSize[T any](c *cache[string, T]) int {
+	if c.index == nil {
+		return len(c.items)
+	}
+	return iterChunk
+}
+
+// rangeItems calls yield for each unexpired item in r, in key order, until it
+// returns false. With a key index, it only holds the read lock while it copies
+// the next few items; without one, it copies all items in r at once.
+func rangeItems[T any](c *cache[string, T], r keyRange, yield func(string, T) bool) {
+	type entry struct {
+		k string
+		x T
+	}
+	var after string
+	resume := false
+	for {
+		c.mu.RLock()
+		keys, more := orderedKeys(c, r, after, resume, chunkSize(c))
+		now := c.now()
+		chunk := make([]entry, 0, len(keys))
+		for _, k := range keys {
+			v, found := c.items[k]
+			if found && (v.Expiration == 0 || now <= v.Expiration) {
+				chunk = append(chunk, entry{k, v.Object})
+			}
+		}
+		c.mu.RUnlock()
+		for _, e := range chunk {
+			if !yield(e.k, e.x) {
+				return
+			}
+		}
+		if !more {
+			return
+		}
+		after, resume = keys[len(keys)-1], true
+	}
+}
+
+// deleteRange deletes all items in r whose keys match, or all of them if
+// match is nil, and returns how many there were. With a key index, it locks
+// the cache for a few items at a time, and calls the eviction functions for
+// them in between; without one, it deletes all of them while holding the lock
+// once, and then calls the eviction functions.
+func deleteRange[T any](c *cache[string, T], r keyRange, match func(string) bool) int {
+	n := 0
+	var after string
+	resume := false
+	for {
+		var evictedItems []evictedItem[string, T]
+		var deletes uint64
+		c.mu.Lock()
+		keys, more := orderedKeys(c, r, after, resume, chunkSize(c))
+		for _, k := range keys {
+			if match != nil && !match(k) {
+				continue
+			}
+			v, found := c.delete(k)
+			if found {
+				deletes++
+				if c.observesEvictions() {
+					evictedItems = append(evictedItems, evictedItem[string, T]{k, v, Deleted})
+				}
+			}
+		}
+		c.mu.Unlock()
+		c.stats.deletes.Add(deletes)
+		c.evicted(evictedItems)
+		n += int(deletes)
+		if !more {
+			return n
+		}
+		after, resume = keys[len(keys)-1], true
+	}
+}
+
+// ScanPrefix returns an iterator over the unexpired items whose keys start
+// with prefix, in key order. Items stored or deleted while it runs may or may
+// not be visited, but because keys are visited in order, none is visited more
+// than once.
+//
+// For caches created using WithKeyIndex, the iterator is fast, and like All,
+// it only locks the cache while it copies the next few items. Otherwise, it
+// scans all keys and copies all matching items at once, holding the read lock
+// while it does.
+func (c *Cache[T]) ScanPrefix(prefix string) iter.Seq2[string, T] {
+	return func(yield func(string, T) bool) {
+		rangeItems(c.cache, prefixRange(prefix), yield)
+	}
+}
+
+// RangeFrom returns an iterator over the unexpired items whose keys are at
+// least start and less than end, in key order. If end is empty, the keys
+// have no upper bound. See ScanPrefix.
+func (c *Cache[T]) RangeFrom(start, end string) iter.Seq2[string, T] {
+	return func(yield func(string, T) bool) {
+		rangeItems(c.cache, keyRange{start, end}, yield)
+	}
+}
+
+// DeletePrefix Delete all items whose keys start with prefix, and return how
+// many there were. For caches created using WithKeyIndex, it is fast, and the
+// eviction functions are called for every few items as they are deleted,
+// without holding the lock. Otherwise, it scans all keys and deletes all
+// matching items while holding the lock, and then calls the eviction
+// functions.
+func (c *Cache[T]) DeletePrefix(prefix string) int {
+	return deleteRange(c.cache, prefixRange(prefix), nil)
+}
+
+// ScanPrefix returns an iterator over the unexpired items in all shards whose
+// keys start with prefix, in key order. See Cache.ScanPrefix.
+func (sc *shardedCache[T]) ScanPrefix(prefix string) iter.Seq2[string, T] {
+	return sc.rangeItems(prefixRange(prefix))
+}
+
+// RangeFrom returns an iterator over the unexpired items in all shards whose
+// keys are at least start and less than end, in key order. See
+// Cache.RangeFrom.
+func (sc *shardedCache[T]) RangeFrom(start, end string) iter.Seq2[string, T] {
+	return sc.rangeItems(keyRange{start, end})
+}
+
+// DeletePrefix Delete all items in all shards whose keys start with prefix,
+// and return how many there were. See Cache.DeletePrefix.
+func (sc *shardedCache[T]) DeletePrefix(prefix string) int {
+	n := 0
+	for _, v := range sc.cs {
+		n += deleteRange(v, prefixRange(prefix), nil)
+	}
+	return n
+}
+
+// rangeItems merges the items in r of all shards in key order.
+func (sc *shardedCache[T]) rangeItems(r keyRange) iter.Seq2[string, T] {
+	return func(yield func(string, T) bool) {
+		type cursor struct {
+			next func() (string, T, bool)
+			k    string
+			x    T
+			ok   bool
+		}
+		cursors := make([]cursor, len(sc.cs))
+		for i, v := range sc.cs {
+			next, stop := iter.Pull2(func(yield func(string, T) bool) {
+				rangeItems(v, r, yield)
+			})
+			defer stop()
+			cursors[i].next = next
+			cursors[i].k, cursors[i].x, cursors[i].ok = next()
+		}
+		for {
+			var first *cursor
+			for i := range cursors {
+				if cursors[i].ok && (first == nil || cursors[i].k < first.k) {
+					first = &cursors[i]
+				}
+			}
+			if first == nil || !yield(first.k, first.x) {
+				return
+			}
+			first.k, first.x, first.ok = first.next()
+		}
+	}
+}
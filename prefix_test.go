@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSkipList(t *testing.T) {
+	l := newSkipList()
+	keys := map[string]bool{}
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		k := fmt.Sprint(rnd.Intn(2000))
+		if rnd.Intn(3) == 0 {
+			l.remove(k)
+			delete(keys, k)
+		} else {
+			l.add(k)
+			keys[k] = true
+		}
+	}
+	var want []string
+	for k := range keys {
+		want = append(want, k)
+	}
+	sort.Strings(want)
+	var got []string
+	for n := l.seek("", false); n != nil; n = n.next[0] {
+		got = append(got, n.key)
+	}
+	if !slices.Equal(got, want) {
+		t.Error("skip list has", len(got), "keys instead of", len(want))
+	}
+	if n := l.seek(want[10], true); n == nil || n.key != want[11] {
+		t.Error("seek after", want[10], "did not return", want[11])
+	}
+	l.clear()
+	if n := l.seek("", false); n != nil {
+		t.Error("clear left", n.key)
+	}
+}
+
+func TestPrefixRange(t *testing.T) {
+	cases := []struct {
+		prefix string
+		r      keyRange
+	}{
+		{"", keyRange{"", ""}},
+		{"user:", keyRange{"user:", "user;"}},
+		{"a\xff", keyRange{"a\xff", "b"}},
+		{"\xff\xff", keyRange{"\xff\xff", ""}},
+	}
+	for _, tt := range cases {
+		if r := prefixRange(tt.prefix); r != tt.r {
+			t.Errorf("prefixRange(%q) is %q", tt.prefix, r)
+		}
+	}
+}
+
+func testScanPrefix(t *testing.T, opts ...Option) {
+	clock := NewFakeClock(time.Now())
+	tc := New[int](DefaultExpiration, 0, append(opts, WithClock(clock))...)
+	for i := 0; i < 300; i++ {
+		tc.Set(fmt.Sprintf("user:%03d:profile", i), i, DefaultExpiration)
+		tc.Set(fmt.Sprintf("group:%03d", i), i, DefaultExpiration)
+	}
+	tc.Set("user:1:expired", 0, time.Second)
+	clock.Advance(2 * time.Second)
+	var keys []string
+	for k := range tc.ScanPrefix("user:1") {
+		keys = append(keys, k)
+	}
+	if len(keys) != 100 || !slices.IsSorted(keys) {
+		t.Error("ScanPrefix returned", len(keys), "keys")
+	}
+	keys = keys[:0]
+	for k, x := range tc.RangeFrom("group:298", "user:000;") {
+		keys = append(keys, fmt.Sprint(k, "=", x))
+	}
+	if !slices.Equal(keys, []string{"group:298=298", "group:299=299", "user:000:profile=0"}) {
+		t.Error("RangeFrom returned", keys)
+	}
+	var evicted []string
+	tc.OnEvicted(func(k string, x int) {
+		// The cache must not be locked.
+		tc.Get(k)
+		evicted = append(evicted, k)
+	})
+	if n := tc.DeletePrefix("user:"); n != 301 {
+		t.Error("DeletePrefix deleted", n, "items")
+	}
+	if len(evicted) != 301 {
+		t.Error("OnEvicted was called for", len(evicted), "items")
+	}
+	if n := tc.ItemCount(); n != 300 {
+		t.Error("ItemCount is not 300:", n)
+	}
+	for range tc.ScanPrefix("user:") {
+		t.Error("ScanPrefix returned a deleted item")
+	}
+}
+
+func TestScanPrefix(t *testing.T) {
+	testScanPrefix(t, WithKeyIndex())
+}
+
+func TestScanPrefixWithoutIndex(t *testing.T) {
+	testScanPrefix(t)
+}
+
+func TestScanPrefixReentrant(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0, WithKeyIndex())
+	for i := 0; i < 200; i++ {
+		tc.Set(fmt.Sprintf("a%03d", i), i, DefaultExpiration)
+	}
+	n := 0
+	withTimeout(t, func() {
+		for k, x := range tc.ScanPrefix("a") {
+			tc.Delete(k)
+			tc.Set(k, x, DefaultExpiration)
+			n++
+		}
+	})
+	if n != 200 {
+		t.Error("ScanPrefix visited", n, "items")
+	}
+}
+
+func TestKeyIndexMaxItems(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0, WithKeyIndex(), WithMaxItems(10))
+	for i := 0; i < 100; i++ {
+		tc.Set(fmt.Sprintf("k%03d", i), i, DefaultExpiration)
+	}
+	n := 0
+	for range tc.ScanPrefix("k") {
+		n++
+	}
+	if n != 10 {
+		t.Error("ScanPrefix visited", n, "items")
+	}
+	tc.Flush()
+	for range tc.ScanPrefix("") {
+		t.Error("ScanPrefix returned a flushed item")
+	}
+}
+
+func TestKeyIndexNewFrom(t *testing.T) {
+	tc := NewFrom(DefaultExpiration, 0, map[string]*Item[int]{
+		"b": {Object: 2},
+		"a": {Object: 1},
+	}, WithKeyIndex())
+	var keys []string
+	for k := range tc.ScanPrefix("") {
+		keys = append(keys, k)
+	}
+	if !slices.Equal(keys, []string{"a", "b"}) {
+		t.Error("ScanPrefix returned", keys)
+	}
+}
+
+func TestKeyIndexKeyType(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewKeyed did not panic")
+		}
+	}()
+	NewKeyed[int, int](DefaultExpiration, 0, WithKeyIndex())
+}
+
+func TestShardedScanPrefix(t *testing.T) {
+	tc := NewSharded[int](DefaultExpiration, 0, 4, WithKeyIndex())
+	for i := 0; i < 300; i++ {
+		tc.Set(fmt.Sprintf("user:%03d", i), i, DefaultExpiration)
+		tc.Set(fmt.Sprintf("group:%03d", i), i, DefaultExpiration)
+	}
+	var keys []string
+	for k := range tc.ScanPrefix("user:1") {
+		keys = append(keys, k)
+	}
+	if len(keys) != 100 || !slices.IsSorted(keys) {
+		t.Error("ScanPrefix returned", len(keys), "keys")
+	}
+	n := 0
+	for range tc.RangeFrom("group:", "") {
+		n++
+		if n == 5 {
+			break
+		}
+	}
+	if n != 5 {
+		t.Error("RangeFrom did not stop after 5 items:", n)
+	}
+	if n := tc.DeletePrefix("group:"); n != 300 {
+		t.Error("DeletePrefix deleted", n, "items")
+	}
+	if n := tc.ItemCount(); n != 300 {
+		t.Error("ItemCount is not 300:", n)
+	}
+}
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// RefreshPolicy makes a cache refresh items in the background instead of
+// letting reads miss them when they expire. When Get finds an item that
+// expires within Ahead, or expired no longer than Grace ago, it returns the
+// cached value immediately and calls Loader in a new goroutine to replace it.
+// Only one refresh (or GetOrLoad loader) runs per key at a time.
+// The new item keeps the tags and cost of the old one, and its sliding
+// expiration, if any. It is not stored if the old one has been deleted in the
+// meantime.
+//
+// Items that have expired, but are still within Grace, are kept by
+// DeleteExpired, but are not returned by other methods, e.g. Items or Add.
+type RefreshPolicy[K comparable, T any] struct {
+	// Ahead is how long before an item expires Get starts refreshing it.
+	Ahead time.Duration
+	// Grace is how long after an item expires Get keeps returning it while it
+	// is being refreshed.
+	Grace time.Duration
+	// Loader returns the new value for k and its expiration duration, which,
+	// like for Set, may be DefaultExpiration or NoExpiration. If it returns
+	// an error, the cached item is left as is.
+	Loader func(ctx context.Context, k K) (T, time.Duration, error)
+}
+
+// serveStale reports whether an item that expired at or before now may still
+// be returned.
+func (p *RefreshPolicy[K, T]) serveStale(item *Item[T], now int64) bool {
+	return p != nil && now <= item.Expiration+int64(p.Grace)
+}
+
+// due reports whether an unexpired or stale item should be refreshed.
+func (p *RefreshPolicy[K, T]) due(item *Item[T], now int64) bool {
+	return p != nil && now > item.Expiration-int64(p.Ahead)
+}
+
+func (p *RefreshPolicy[K, T]) grace() int64 {
+	if p == nil {
+		return 0
+	}
+	return int64(p.Grace)
+}
+
+// SetRefreshPolicy Sets an (optional) policy for refreshing items before, or
+// shortly after, they expire. Set to nil to disable.
+func (c *cache[K, T]) SetRefreshPolicy(p *RefreshPolicy[K, T]) {
+	c.mu.Lock()
+	c.refresh = p
+	c.mu.Unlock()
+}
+
+// startRefresh reloads k in a new goroutine, unless it is already being
+// loaded.
+func (c *cache[K, T]) startRefresh(k K, p *RefreshPolicy[K, T]) {
+	cl, leader := c.loads.start(k)
+	if !leader {
+		return
+	}
+	go c.loads.run(k, cl, func() (T, error) {
+		x, d, err := p.Loader(context.Background(), k)
+		c.stats.countLoad(err)
+		if err != nil {
+			return x, err
+		}
+		c.storeRefreshed(k, x, d)
+		return x, nil
+	})
+}
+
+// storeRefreshed stores x, the new value of k, like Set, except that the new
+// item keeps the tags and cost of the old one, and its sliding expiration, if
+// any. Nothing is stored if the old item has been deleted in the meantime,
+// e.g. by InvalidateTag.
+func (c *cache[K, T]) storeRefreshed(k K, x T, d time.Duration) {
+	var e int64
+	if d == DefaultExpiration {
+		d = c.defaultExpiration
+	}
+	now := c.clock.Now()
+	c.mu.Lock()
+	old, found := c.items[k]
+	if !found {
+		c.mu.Unlock()
+		return
+	}
+	var sd time.Duration
+	if d > 0 {
+		e = now.Add(d).UnixNano()
+		if c.sliding || old.Sliding > 0 {
+			sd = d
+		}
+	}
+	evictedItems := c.insert(k, &Item[T]{
+		Object:     x,
+		Expiration: e,
+		Sliding:    sd,
+		Cost:       old.Cost,
+		Tags:       old.Tags,
+	})
+	c.mu.Unlock()
+	c.evicted(evictedItems)
+}
@@ -0,0 +1,178 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitFor polls the cache until k has the value x, or fails after a second.
+func waitFor[T comparable](t *testing.T, tc *Cache[T], k string, x T) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if y, found := tc.Get(k); found && y == x {
+			return
+		}
+		<-time.After(time.Millisecond)
+	}
+	t.Fatal(k, "was not refreshed to", x)
+}
+
+func TestRefreshAhead(t *testing.T) {
+	tc := New[string](DefaultExpiration, 0)
+	var calls int32
+	release := make(chan bool)
+	tc.SetRefreshPolicy(&RefreshPolicy[string, string]{
+		Ahead: time.Hour,
+		Loader: func(ctx context.Context, k string) (string, time.Duration, error) {
+			atomic.AddInt32(&calls, 1)
+			<-release
+			return "new", NoExpiration, nil
+		},
+	})
+	tc.Set("a", "old", 30*time.Minute)
+	for i := 0; i < 10; i++ {
+		x, found := tc.Get("a")
+		if !found || x != "old" {
+			t.Fatal("a is not old while it is being refreshed:", x)
+		}
+	}
+	close(release)
+	waitFor(t, tc, "a", "new")
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Error("loader was called more than once:", n)
+	}
+
+	tc.Set("b", "old", 2*time.Hour)
+	tc.Get("b")
+	<-time.After(10 * time.Millisecond)
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Error("b was refreshed even though it doesn't expire within an hour")
+	}
+}
+
+func TestRefreshGrace(t *testing.T) {
+	tc := New[string](DefaultExpiration, 0)
+	tc.SetRefreshPolicy(&RefreshPolicy[string, string]{
+		Grace: time.Hour,
+		Loader: func(ctx context.Context, k string) (string, time.Duration, error) {
+			return "new", NoExpiration, nil
+		},
+	})
+	tc.Set("a", "old", time.Millisecond)
+	<-time.After(5 * time.Millisecond)
+	tc.DeleteExpired()
+	if n := tc.ItemCount(); n != 1 {
+		t.Fatal("a was deleted during its grace period")
+	}
+	x, expiration, found := tc.GetWithExpiration("a")
+	if !found || x.(string) != "old" {
+		t.Fatal("stale a was not returned:", x)
+	}
+	if expiration.After(time.Now()) {
+		t.Error("expiration of stale a is in the future")
+	}
+	waitFor(t, tc, "a", "new")
+}
+
+func TestRefreshGraceOver(t *testing.T) {
+	tc := New[string](DefaultExpiration, 0)
+	tc.SetRefreshPolicy(&RefreshPolicy[string, string]{
+		Grace: time.Millisecond,
+		Loader: func(ctx context.Context, k string) (string, time.Duration, error) {
+			t.Error("a was refreshed after its grace period")
+			return "new", NoExpiration, nil
+		},
+	})
+	tc.Set("a", "old", time.Millisecond)
+	<-time.After(5 * time.Millisecond)
+	if _, found := tc.Get("a"); found {
+		t.Error("a was found after its grace period")
+	}
+	tc.DeleteExpired()
+	if n := tc.ItemCount(); n != 0 {
+		t.Error("a was not deleted after its grace period")
+	}
+}
+
+func TestRefreshError(t *testing.T) {
+	tc := New[string](DefaultExpiration, 0)
+	done := make(chan bool, 1)
+	tc.SetRefreshPolicy(&RefreshPolicy[string, string]{
+		Ahead: time.Hour,
+		Loader: func(ctx context.Context, k string) (string, time.Duration, error) {
+			select {
+			case done <- true:
+			default:
+			}
+			return "", DefaultExpiration, errors.New("database is down")
+		},
+	})
+	tc.Set("a", "old", time.Minute)
+	tc.Get("a")
+	<-done
+	<-time.After(5 * time.Millisecond)
+	if x, found := tc.Get("a"); !found || x != "old" {
+		t.Error("a was changed by a failed refresh:", x)
+	}
+}
+
+func TestRefreshKeepsItem(t *testing.T) {
+	tc := New[string](DefaultExpiration, 0, WithMaxCost(100))
+	release := make(chan bool)
+	tc.SetRefreshPolicy(&RefreshPolicy[string, string]{
+		Ahead: time.Hour,
+		Loader: func(ctx context.Context, k string) (string, time.Duration, error) {
+			<-release
+			return "new", 30 * time.Minute, nil
+		},
+	})
+	tc.SetWithTags("page", "old", 30*time.Minute, "product:7")
+	tc.SetWithCost("blob", "old", 30*time.Minute, 60)
+	tc.SetSliding("session", "old", 30*time.Minute)
+	for _, k := range []string{"page", "blob", "session"} {
+		tc.Get(k)
+	}
+	close(release)
+	for _, k := range []string{"page", "blob", "session"} {
+		waitFor(t, tc, k, "new")
+	}
+	if n := tc.TotalCost(); n != 62 {
+		t.Error("TotalCost is not 62:", n)
+	}
+	if item := tc.Items()["session"]; item.Sliding != 30*time.Minute {
+		t.Error("session does not slide:", item.Sliding)
+	}
+	if n := tc.InvalidateTag("product:7"); n != 1 {
+		t.Error("InvalidateTag deleted", n, "refreshed items")
+	}
+}
+
+func TestRefreshInvalidated(t *testing.T) {
+	tc := New[string](DefaultExpiration, 0)
+	started := make(chan bool)
+	release := make(chan bool)
+	done := make(chan bool)
+	tc.SetRefreshPolicy(&RefreshPolicy[string, string]{
+		Ahead: time.Hour,
+		Loader: func(ctx context.Context, k string) (string, time.Duration, error) {
+			defer close(done)
+			started <- true
+			<-release
+			return "new", NoExpiration, nil
+		},
+	})
+	tc.SetWithTags("page", "old", 30*time.Minute, "product:7")
+	tc.Get("page")
+	<-started
+	tc.InvalidateTag("product:7")
+	close(release)
+	<-done
+	<-time.After(5 * time.Millisecond)
+	if x, found := tc.Get("page"); found {
+		t.Error("refresh stored an invalidated item:", x)
+	}
+}
@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"encoding/json"
+	"expvar"
+	"sync"
+	"time"
+)
+
+// DefaultRegistry is the registry that caches created using WithName add
+// themselves to. It is published under expvar as "go-cache", so the caches in
+// it show up in /debug/vars, unless the program has already published another
+// variable under that name.
+var DefaultRegistry = NewRegistry()
+
+// expvarName is the name DefaultRegistry is published under.
+const expvarName = "go-cache"
+
+func init() {
+	// expvar.Publish panics if the name is taken.
+	if expvar.Get(expvarName) == nil {
+		expvar.Publish(expvarName, DefaultRegistry)
+	}
+}
+
+// Registry is a set of named caches. It implements expvar.Var: its String
+// method returns a JSON object with the items count, configuration and
+// statistics of every cache in it, keyed by name. Durations are reported in
+// nanoseconds. Use NewMetricsHandler to serve the same statistics to
+// Prometheus.
+type Registry struct {
+	mu     sync.RWMutex
+	caches map[string]StatsSource
+}
+
+// NewRegistry returns an empty registry. Use expvar.Publish to publish it.
+func NewRegistry() *Registry {
+	return &Registry{caches: map[string]StatsSource{}}
+}
+
+// Register Adds a cache to the registry under the given name, replacing any
+// cache previously registered under that name.
+func (r *Registry) Register(name string, c StatsSource) {
+	r.mu.Lock()
+	r.caches[name] = c
+	r.mu.Unlock()
+}
+
+// Unregister Removes the cache registered under the given name, if any.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	delete(r.caches, name)
+	r.mu.Unlock()
+}
+
+// unregister removes the cache registered under the given name if it is c,
+// so that closing a cache doesn't remove another one that took its name.
+func (r *Registry) unregister(name string, c StatsSource) {
+	r.mu.Lock()
+	if r.caches[name] == c {
+		delete(r.caches, name)
+	}
+	r.mu.Unlock()
+}
+
+// Names Returns the names of all caches in the registry, in no particular
+// order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.caches))
+	for name := range r.caches {
+		names = append(names, name)
+	}
+	r.mu.RUnlock()
+	return names
+}
+
+// each calls f for each cache in the registry, in no particular order, while
+// holding the read lock.
+func (r *Registry) each(f func(name string, c StatsSource)) {
+	r.mu.RLock()
+	for name, c := range r.caches {
+		f(name, c)
+	}
+	r.mu.RUnlock()
+}
+
+// registryVar is what a Registry reports about each of its caches.
+type registryVar struct {
+	Items             int
+	DefaultExpiration time.Duration `json:",omitempty"`
+	CleanupInterval   time.Duration `json:",omitempty"`
+	Stats             Stats
+}
+
+// configurer is implemented by the caches of this package, so that a
+// Registry can report their configuration.
+type configurer interface {
+	config() (defaultExpiration, cleanupInterval time.Duration)
+}
+
+// String Returns the registry's caches as JSON. See Registry.
+func (r *Registry) String() string {
+	vars := map[string]registryVar{}
+	r.each(func(name string, c StatsSource) {
+		v := registryVar{Items: c.ItemCount(), Stats: c.Stats()}
+		if cc, ok := c.(configurer); ok {
+			v.DefaultExpiration, v.CleanupInterval = cc.config()
+		}
+		vars[name] = v
+	})
+	b, err := json.Marshal(vars)
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
+
+func (c *cache[K, T]) config() (time.Duration, time.Duration) {
+	var ci time.Duration
+	if c.janitor != nil {
+		ci = c.janitor.Interval
+	}
+	return c.defaultExpiration, ci
+}
+
+// register adds the cache to DefaultRegistry, unless name is empty.
+func (c *cache[K, T]) register(name string) {
+	if name == "" {
+		return
+	}
+	c.name = name
+	DefaultRegistry.Register(name, c)
+}
+
+func (sc *shardedCache[T]) config() (time.Duration, time.Duration) {
+	var ci time.Duration
+	if sc.janitor != nil {
+		ci = sc.janitor.Interval
+	}
+	return sc.cs[0].defaultExpiration, ci
+}
+
+// register adds the cache to DefaultRegistry, unless name is empty.
+func (sc *shardedCache[T]) register(name string) {
+	if name == "" {
+		return
+	}
+	sc.name = name
+	DefaultRegistry.Register(name, sc)
+}
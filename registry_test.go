@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	tc := New[int](time.Minute, time.Hour, WithName("test-registry"))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Get("a")
+	sc := NewSharded[int](DefaultExpiration, 0, 2, WithName("test-registry-sharded"))
+	sc.Set("a", 1, DefaultExpiration)
+	sc.Set("b", 2, DefaultExpiration)
+
+	v := expvar.Get(expvarName)
+	if v == nil {
+		t.Fatal("DefaultRegistry is not published")
+	}
+	var vars map[string]registryVar
+	if err := json.Unmarshal([]byte(v.String()), &vars); err != nil {
+		t.Fatal("couldn't decode registry:", err)
+	}
+	want := registryVar{
+		Items:             1,
+		DefaultExpiration: time.Minute,
+		CleanupInterval:   time.Hour,
+		Stats:             Stats{Hits: 1, Sets: 1},
+	}
+	if got := vars["test-registry"]; got != want {
+		t.Errorf("registry reports %+v, not %+v", got, want)
+	}
+	if got := vars["test-registry-sharded"]; got.Items != 2 || got.Stats.Sets != 2 || got.DefaultExpiration != NoExpiration {
+		t.Errorf("registry reports %+v for the sharded cache", got)
+	}
+
+	tc.Close()
+	sc.Close()
+	for _, name := range DefaultRegistry.Names() {
+		if name == "test-registry" || name == "test-registry-sharded" {
+			t.Error("closed cache is still registered:", name)
+		}
+	}
+}
+
+func TestRegistryCloseKeepsReplacement(t *testing.T) {
+	old := New[int](DefaultExpiration, 0, WithName("test-registry-replaced"))
+	tc := New[int](DefaultExpiration, 0, WithName("test-registry-replaced"))
+	defer tc.Close()
+	old.Close()
+	found := false
+	for _, name := range DefaultRegistry.Names() {
+		found = found || name == "test-registry-replaced"
+	}
+	if !found {
+		t.Error("closing a cache removed the cache that replaced it")
+	}
+}
+
+func TestRegistryContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	New[int](DefaultExpiration, 0, WithName("test-registry-cancelled"), WithContext(ctx))
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		registered := false
+		for _, name := range DefaultRegistry.Names() {
+			registered = registered || name == "test-registry-cancelled"
+		}
+		if !registered {
+			return
+		}
+		<-time.After(time.Millisecond)
+	}
+	t.Error("cache is still registered after its context was cancelled")
+}
+
+func TestRegistryManual(t *testing.T) {
+	r := NewRegistry()
+	tc := New[string](DefaultExpiration, 0)
+	r.Register("manual", tc)
+	tc.Set("a", "b", DefaultExpiration)
+	var vars map[string]registryVar
+	if err := json.Unmarshal([]byte(r.String()), &vars); err != nil {
+		t.Fatal("couldn't decode registry:", err)
+	}
+	if vars["manual"].Items != 1 || vars["manual"].DefaultExpiration != NoExpiration {
+		t.Errorf("registry reports %+v", vars["manual"])
+	}
+	r.Unregister("manual")
+	if r.String() != "{}" {
+		t.Error("registry is not empty after Unregister:", r.String())
+	}
+}
@@ -1,33 +1,49 @@
 package cache
 
 import (
+	"context"
 	"crypto/rand"
+	"encoding/gob"
+	"fmt"
+	"io"
 	"math"
 	"math/big"
 	insecurerand "math/rand"
 	"os"
 	"runtime"
+	"sync"
 	"time"
 )
 
-// This is an experimental and unexported (for now) attempt at making a cache
-// with better algorithmic complexity than the standard one, namely by
-// preventing write locks of the entire cache when an item is added. As of the
-// time of writing, the overhead of selecting buckets results in cache
-// operations being about twice as slow as for the standard cache with small
-// total cache sizes, and faster for larger ones.
+// ShardedCache is a cache with better algorithmic complexity than the standard
+// one, namely by preventing write locks of the entire cache when an item is
+// added. Keys are spread over a number of shards, each of which is a cache
+// with its own lock. As of the time of writing, the overhead of selecting
+// shards results in cache operations being about twice as slow as for the
+// standard cache with small total cache sizes, and faster for larger ones,
+// especially when many goroutines use the cache at once.
 //
-// See cache_test.go for a few benchmarks.
-
-type unexportedShardedCache[T any] struct {
+// ShardedCache has the same methods as Cache. Methods that operate on all
+// items (e.g. Items, Range and Flush) visit the shards one at a time, so they
+// don't correspond to a consistent snapshot of the whole cache.
+//
+// See sharded_test.go for a few benchmarks.
+type ShardedCache[T any] struct {
 	*shardedCache[T]
+	// If this is confusing, see the comment at the bottom of New()
 }
 
 type shardedCache[T any] struct {
 	seed    uint32
 	m       uint32
-	cs      []*cache[T]
-	janitor *shardedJanitor[T]
+	cs      []*cache[string, T]
+	janitor *janitor
+	stats   stats // of the janitor; the shards count everything else
+	name    string
+
+	mu          sync.Mutex
+	closed      bool
+	stopContext func() bool
 }
 
 // djb2 with better shuffling. 5x faster than FNV with the hash.Hash overhead.
@@ -62,7 +78,7 @@ func djb33(seed uint32, k string) uint32 {
 	return d ^ (d >> 16)
 }
 
-func (sc *shardedCache[T]) bucket(k string) *cache[T] {
+func (sc *shardedCache[T]) bucket(k string) *cache[string, T] {
 	return sc.cs[djb33(sc.seed, k)%sc.m]
 }
 
@@ -70,6 +86,26 @@ func (sc *shardedCache[T]) Set(k string, x T, d time.Duration) {
 	sc.bucket(k).Set(k, x, d)
 }
 
+func (sc *shardedCache[T]) SetSliding(k string, x T, d time.Duration) {
+	sc.bucket(k).SetSliding(k, x, d)
+}
+
+func (sc *shardedCache[T]) SetDefault(k string, x T) {
+	sc.bucket(k).SetDefault(k, x)
+}
+
+func (sc *shardedCache[T]) Compute(k string, f func(old T, found bool) (T, time.Duration, bool)) (T, bool) {
+	return sc.bucket(k).Compute(k, f)
+}
+
+func (sc *shardedCache[T]) Update(k string, f func(old T, found bool) (T, bool)) (T, bool) {
+	return sc.bucket(k).Update(k, f)
+}
+
+func (sc *shardedCache[T]) SetWithCost(k string, x T, d time.Duration, cost int64) {
+	sc.bucket(k).SetWithCost(k, x, d, cost)
+}
+
 func (sc *shardedCache[T]) Add(k string, x T, d time.Duration) error {
 	return sc.bucket(k).Add(k, x, d)
 }
@@ -82,6 +118,26 @@ func (sc *shardedCache[T]) Get(k string) (T, bool) {
 	return sc.bucket(k).Get(k)
 }
 
+func (sc *shardedCache[T]) GetOrLoad(ctx context.Context, k string, loader func(ctx context.Context) (T, time.Duration, error)) (T, error) {
+	return sc.bucket(k).GetOrLoad(ctx, k, loader)
+}
+
+func (sc *shardedCache[T]) GetWithVersion(k string) (T, uint64, bool) {
+	return sc.bucket(k).GetWithVersion(k)
+}
+
+func (sc *shardedCache[T]) CompareAndSwap(k string, version uint64, x T, d time.Duration) bool {
+	return sc.bucket(k).CompareAndSwap(k, version, x, d)
+}
+
+func (sc *shardedCache[T]) CompareAndDelete(k string, version uint64) bool {
+	return sc.bucket(k).CompareAndDelete(k, version)
+}
+
+func (sc *shardedCache[T]) GetWithExpiration(k string) (interface{}, time.Time, bool) {
+	return sc.bucket(k).GetWithExpiration(k)
+}
+
 func (sc *shardedCache[T]) Increment(k string, n int64) error {
 	return sc.bucket(k).Increment(k, n)
 }
@@ -90,10 +146,126 @@ func (sc *shardedCache[T]) IncrementFloat(k string, n float64) error {
 	return sc.bucket(k).IncrementFloat(k, n)
 }
 
+func (sc *shardedCache[T]) IncrementInt(k string, n int) (int, error) {
+	return sc.bucket(k).IncrementInt(k, n)
+}
+
+func (sc *shardedCache[T]) IncrementInt8(k string, n int8) (int8, error) {
+	return sc.bucket(k).IncrementInt8(k, n)
+}
+
+func (sc *shardedCache[T]) IncrementInt16(k string, n int16) (int16, error) {
+	return sc.bucket(k).IncrementInt16(k, n)
+}
+
+func (sc *shardedCache[T]) IncrementInt32(k string, n int32) (int32, error) {
+	return sc.bucket(k).IncrementInt32(k, n)
+}
+
+func (sc *shardedCache[T]) IncrementInt64(k string, n int64) (int64, error) {
+	return sc.bucket(k).IncrementInt64(k, n)
+}
+
+func (sc *shardedCache[T]) IncrementUint(k string, n uint) (uint, error) {
+	return sc.bucket(k).IncrementUint(k, n)
+}
+
+func (sc *shardedCache[T]) IncrementUintptr(k string, n uintptr) (uintptr, error) {
+	return sc.bucket(k).IncrementUintptr(k, n)
+}
+
+func (sc *shardedCache[T]) IncrementUint8(k string, n uint8) (uint8, error) {
+	return sc.bucket(k).IncrementUint8(k, n)
+}
+
+func (sc *shardedCache[T]) IncrementUint16(k string, n uint16) (uint16, error) {
+	return sc.bucket(k).IncrementUint16(k, n)
+}
+
+func (sc *shardedCache[T]) IncrementUint32(k string, n uint32) (uint32, error) {
+	return sc.bucket(k).IncrementUint32(k, n)
+}
+
+func (sc *shardedCache[T]) IncrementUint64(k string, n uint64) (uint64, error) {
+	return sc.bucket(k).IncrementUint64(k, n)
+}
+
+func (sc *shardedCache[T]) IncrementFloat32(k string, n float32) (float32, error) {
+	return sc.bucket(k).IncrementFloat32(k, n)
+}
+
+func (sc *shardedCache[T]) IncrementFloat64(k string, n float64) (float64, error) {
+	return sc.bucket(k).IncrementFloat64(k, n)
+}
+
+func (sc *shardedCache[T]) IncrementWithMode(k string, n int64, mode OverflowMode) (T, error) {
+	return sc.bucket(k).IncrementWithMode(k, n, mode)
+}
+
+func (sc *shardedCache[T]) DecrementWithMode(k string, n int64, mode OverflowMode) (T, error) {
+	return sc.bucket(k).DecrementWithMode(k, n, mode)
+}
+
 func (sc *shardedCache[T]) Decrement(k string, n int64) error {
 	return sc.bucket(k).Decrement(k, n)
 }
 
+func (sc *shardedCache[T]) DecrementFloat(k string, n float64) error {
+	return sc.bucket(k).DecrementFloat(k, n)
+}
+
+func (sc *shardedCache[T]) DecrementInt(k string, n int) (int, error) {
+	return sc.bucket(k).DecrementInt(k, n)
+}
+
+func (sc *shardedCache[T]) DecrementInt8(k string, n int8) (int8, error) {
+	return sc.bucket(k).DecrementInt8(k, n)
+}
+
+func (sc *shardedCache[T]) DecrementInt16(k string, n int16) (int16, error) {
+	return sc.bucket(k).DecrementInt16(k, n)
+}
+
+func (sc *shardedCache[T]) DecrementInt32(k string, n int32) (int32, error) {
+	return sc.bucket(k).DecrementInt32(k, n)
+}
+
+func (sc *shardedCache[T]) DecrementInt64(k string, n int64) (int64, error) {
+	return sc.bucket(k).DecrementInt64(k, n)
+}
+
+func (sc *shardedCache[T]) DecrementUint(k string, n uint) (uint, error) {
+	return sc.bucket(k).DecrementUint(k, n)
+}
+
+func (sc *shardedCache[T]) DecrementUintptr(k string, n uintptr) (uintptr, error) {
+	return sc.bucket(k).DecrementUintptr(k, n)
+}
+
+func (sc *shardedCache[T]) DecrementUint8(k string, n uint8) (uint8, error) {
+	return sc.bucket(k).DecrementUint8(k, n)
+}
+
+func (sc *shardedCache[T]) DecrementUint16(k string, n uint16) (uint16, error) {
+	return sc.bucket(k).DecrementUint16(k, n)
+}
+
+func (sc *shardedCache[T]) DecrementUint32(k string, n uint32) (uint32, error) {
+	return sc.bucket(k).DecrementUint32(k, n)
+}
+
+func (sc *shardedCache[T]) DecrementUint64(k string, n uint64) (uint64, error) {
+	return sc.bucket(k).DecrementUint64(k, n)
+}
+
+func (sc *shardedCache[T]) DecrementFloat32(k string, n float32) (float32, error) {
+	return sc.bucket(k).DecrementFloat32(k, n)
+}
+
+func (sc *shardedCache[T]) DecrementFloat64(k string, n float64) (float64, error) {
+	return sc.bucket(k).DecrementFloat64(k, n)
+}
+
 func (sc *shardedCache[T]) Delete(k string) {
 	sc.bucket(k).Delete(k)
 }
@@ -104,56 +276,176 @@ func (sc *shardedCache[T]) DeleteExpired() {
 	}
 }
 
-// Items Returns the items in the cache. This may include items that have expired,
-// but have not yet been cleaned up. If this is significant, the Expiration
-// fields of the items should be checked. Note that explicit synchronization
-// is needed to use a cache and its corresponding Items() return values at
-// the same time, as the maps are shared.
-func (sc *shardedCache[T]) Items() []map[string]*Item[T] {
-	res := make([]map[string]*Item[T], len(sc.cs))
-	for i, v := range sc.cs {
-		res[i] = v.Items()
+// OnEvicted Sets an (optional) function that is called with the key and value
+// when an item is evicted from any of the shards. Set to nil to disable.
+func (sc *shardedCache[T]) OnEvicted(f func(string, T)) {
+	for _, v := range sc.cs {
+		v.OnEvicted(f)
 	}
-	return res
 }
 
-func (sc *shardedCache[T]) Flush() {
+// OnEvictedWithReason Sets an (optional) function that is called with the key,
+// a copy of the item and the reason whenever an item is removed from any of
+// the shards. Set to nil to disable.
+func (sc *shardedCache[T]) OnEvictedWithReason(f func(k string, item Item[T], reason EvictionReason)) {
 	for _, v := range sc.cs {
-		v.Flush()
+		v.OnEvictedWithReason(f)
 	}
 }
 
-type shardedJanitor[T any] struct {
-	Interval time.Duration
-	stop     chan bool
+// SetRefreshPolicy Sets an (optional) policy for refreshing items before, or
+// shortly after, they expire, for all shards. Set to nil to disable.
+func (sc *shardedCache[T]) SetRefreshPolicy(p *RefreshPolicy[string, T]) {
+	for _, v := range sc.cs {
+		v.SetRefreshPolicy(p)
+	}
+}
+
+// Save Write the items of all shards (using Gob) to an io.Writer, in the same
+// format as Cache.Save.
+//
+// NOTE: This method is deprecated in favor of sc.Items() and NewFrom() (see the
+// documentation for NewFrom().)
+func (sc *shardedCache[T]) Save(w io.Writer) (err error) {
+	enc := gob.NewEncoder(w)
+	defer func() {
+		if x := recover(); x != nil {
+			err = fmt.Errorf("error registering item types with Gob library")
+		}
+	}()
+	items := map[string]*Item[T]{}
+	for _, v := range sc.cs {
+		v.mu.RLock()
+		for k, item := range v.items {
+			items[k] = item
+		}
+		v.mu.RUnlock()
+	}
+	for _, v := range items {
+		gob.Register(v.Object)
+	}
+	err = enc.Encode(&items)
+	return
+}
+
+// SaveFile Save the items of all shards to the given filename, creating the
+// file if it doesn't exist, and overwriting it if it does.
+//
+// NOTE: This method is deprecated in favor of sc.Items() and NewFrom() (see the
+// documentation for NewFrom().)
+func (sc *shardedCache[T]) SaveFile(fName string) error {
+	fp, err := os.Create(fName)
+	if err != nil {
+		return err
+	}
+	err = sc.Save(fp)
+	if err != nil {
+		_ = fp.Close()
+		return err
+	}
+	return fp.Close()
 }
 
-func (j *shardedJanitor[T]) Run(sc *shardedCache[T]) {
-	j.stop = make(chan bool)
-	tick := time.Tick(j.Interval)
-	for {
-		select {
-		case <-tick:
-			sc.DeleteExpired()
-		case <-j.stop:
+// Load Add (Gob-serialized) cache items from an io.Reader, excluding any items
+// with keys that already exist (and haven't expired) in the current cache.
+// Items written by Cache.Save can be loaded, and vice versa.
+//
+// NOTE: This method is deprecated in favor of sc.Items() and NewFrom() (see the
+// documentation for NewFrom().)
+func (sc *shardedCache[T]) Load(r io.Reader) error {
+	dec := gob.NewDecoder(r)
+	items := map[string]*Item[T]{}
+	err := dec.Decode(&items)
+	if err == nil {
+		shards := make([]map[string]*Item[T], len(sc.cs))
+		for k, v := range items {
+			i := djb33(sc.seed, k) % sc.m
+			if shards[i] == nil {
+				shards[i] = map[string]*Item[T]{}
+			}
+			shards[i][k] = v
+		}
+		for i, m := range shards {
+			if m != nil {
+				if err = sc.cs[i].load(m); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return err
+}
+
+// LoadFile Load and add cache items from the given filename, excluding any
+// items with keys that already exist in the current cache.
+//
+// NOTE: This method is deprecated in favor of sc.Items() and NewFrom() (see the
+// documentation for NewFrom().)
+func (sc *shardedCache[T]) LoadFile(fName string) error {
+	fp, err := os.Open(fName)
+	if err != nil {
+		return err
+	}
+	err = sc.Load(fp)
+	if err != nil {
+		_ = fp.Close()
+		return err
+	}
+	return fp.Close()
+}
+
+// Items Copies all unexpired items in all shards into a new map and returns it.
+func (sc *shardedCache[T]) Items() map[string]*Item[T] {
+	m := map[string]*Item[T]{}
+	for _, v := range sc.cs {
+		for k, item := range v.Items() {
+			m[k] = item
+		}
+	}
+	return m
+}
+
+// Range calls f sequentially for each key and value present in the cache, one
+// shard at a time. If f returns false, range stops the iteration. See
+// Cache.Range for its consistency guarantees.
+func (sc *shardedCache[T]) Range(f func(key string, value T) bool) {
+	ok := true
+	for _, v := range sc.cs {
+		v.Range(func(k string, x T) bool {
+			ok = f(k, x)
+			return ok
+		})
+		if !ok {
 			return
 		}
 	}
 }
 
-func stopShardedJanitor[T any](sc *unexportedShardedCache[T]) {
-	sc.janitor.stop <- true
+// ItemCount Returns the number of items in all shards. This may include items
+// that have expired, but have not yet been cleaned up.
+func (sc *shardedCache[T]) ItemCount() int {
+	n := 0
+	for _, v := range sc.cs {
+		n += v.ItemCount()
+	}
+	return n
+}
+
+func (sc *shardedCache[T]) TotalCost() int64 {
+	var n int64
+	for _, v := range sc.cs {
+		n += v.TotalCost()
+	}
+	return n
 }
 
-func runShardedJanitor[T any](sc *shardedCache[T], ci time.Duration) {
-	j := &shardedJanitor[T]{
-		Interval: ci,
+func (sc *shardedCache[T]) Flush() {
+	for _, v := range sc.cs {
+		v.Flush()
 	}
-	sc.janitor = j
-	go j.Run(sc)
 }
 
-func newShardedCache[T any](n int, de time.Duration) *shardedCache[T] {
+func newShardedCache[T any](n int, de time.Duration, o *options) *shardedCache[T] {
 	maxUint := big.NewInt(0).SetUint64(uint64(math.MaxUint32))
 	rnd, err := rand.Int(rand.Reader, maxUint)
 	var seed uint32
@@ -166,27 +458,50 @@ func newShardedCache[T any](n int, de time.Duration) *shardedCache[T] {
 	sc := &shardedCache[T]{
 		seed: seed,
 		m:    uint32(n),
-		cs:   make([]*cache[T], n),
+		cs:   make([]*cache[string, T], n),
+	}
+	// Limits apply to each shard separately, so split them evenly. The
+	// shards are closed and registered by the sharded cache, not by
+	// themselves.
+	so := *o
+	if so.maxItems > 0 {
+		so.maxItems = (so.maxItems + n - 1) / n
 	}
+	if so.maxCost > 0 {
+		so.maxCost = (so.maxCost + int64(n) - 1) / int64(n)
+	}
+	so.ctx = nil
+	so.name = ""
 	for i := 0; i < n; i++ {
-		c := &cache[T]{
-			defaultExpiration: de,
-			items:             map[string]*Item[T]{},
-		}
-		sc.cs[i] = c
+		sc.cs[i] = newCache(de, map[string]*Item[T]{}, &so)
 	}
 	return sc
 }
 
-func unexportedNewSharded[T any](defaultExpiration, cleanupInterval time.Duration, shards int) *unexportedShardedCache[T] {
+// NewSharded Return a new cache whose items are spread over the given number of
+// shards (at least one.) The default expiration duration, cleanup interval
+// and options behave as for New. Limits set by options, e.g. WithMaxItems,
+// are divided evenly between the shards, so they are enforced per shard
+// rather than for the cache as a whole.
+func NewSharded[T any](defaultExpiration, cleanupInterval time.Duration, shards int, opts ...Option) *ShardedCache[T] {
 	if defaultExpiration == 0 {
 		defaultExpiration = -1
 	}
-	sc := newShardedCache[T](shards, defaultExpiration)
-	SC := &unexportedShardedCache[T]{sc}
+	if shards < 1 {
+		shards = 1
+	}
+	o := newOptions(opts)
+	sc := newShardedCache[T](shards, defaultExpiration, o)
+	SC := &ShardedCache[T]{sc}
 	if cleanupInterval > 0 {
-		runShardedJanitor(sc, cleanupInterval)
-		runtime.SetFinalizer(SC, stopShardedJanitor[T])
+		sc.janitor = runJanitor(o.clock, cleanupInterval, sc.DeleteExpired, &sc.stats)
+		runtime.SetFinalizer(SC, func(SC *ShardedCache[T]) {
+			_ = SC.Close()
+		})
+	}
+	sc.register(o.name)
+	if o.ctx != nil {
+		sc.closeWhenDone(o.ctx)
 	}
 	return SC
 }
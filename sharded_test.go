@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"bytes"
 	"strconv"
 	"sync"
 	"testing"
@@ -27,10 +28,197 @@ var shardedKeys = []string{
 }
 
 func TestShardedCache(t *testing.T) {
-	tc := unexportedNewSharded[string](DefaultExpiration, 0, 13)
+	tc := NewSharded[string](DefaultExpiration, 0, 13)
 	for _, v := range shardedKeys {
 		tc.Set(v, "value", DefaultExpiration)
 	}
+	for _, v := range shardedKeys {
+		x, found := tc.Get(v)
+		if !found {
+			t.Error(v, "was not found")
+		}
+		if x != "value" {
+			t.Error(v, "is not value:", x)
+		}
+	}
+	if n := tc.ItemCount(); n != len(shardedKeys) {
+		t.Errorf("Item count is not %d: %d", len(shardedKeys), n)
+	}
+	if n := len(tc.Items()); n != len(shardedKeys) {
+		t.Errorf("Items has %d items instead of %d", n, len(shardedKeys))
+	}
+	tc.Flush()
+	if n := tc.ItemCount(); n != 0 {
+		t.Errorf("Item count after Flush is not 0: %d", n)
+	}
+}
+
+func TestShardedCacheAddReplaceDelete(t *testing.T) {
+	tc := NewSharded[string](DefaultExpiration, 0, 4)
+	if err := tc.Replace("foo", "bar", DefaultExpiration); err == nil {
+		t.Error("Replaced foo when it shouldn't exist")
+	}
+	if err := tc.Add("foo", "bar", DefaultExpiration); err != nil {
+		t.Error("Couldn't add foo even though it shouldn't exist")
+	}
+	if err := tc.Add("foo", "baz", DefaultExpiration); err == nil {
+		t.Error("Successfully added another foo when it should have returned an error")
+	}
+	if err := tc.Replace("foo", "baz", DefaultExpiration); err != nil {
+		t.Error("Couldn't replace existing key foo")
+	}
+	x, _ := tc.Get("foo")
+	if x != "baz" {
+		t.Error("foo is not baz:", x)
+	}
+	var evicted string
+	tc.OnEvicted(func(k string, v string) {
+		evicted = k + "=" + v
+	})
+	tc.Delete("foo")
+	if _, found := tc.Get("foo"); found {
+		t.Error("foo was found, but it should have been deleted")
+	}
+	if evicted != "foo=baz" {
+		t.Error("evicted item is not foo=baz:", evicted)
+	}
+}
+
+func TestShardedCacheGetWithExpiration(t *testing.T) {
+	tc := NewSharded[int](DefaultExpiration, 0, 4)
+	tc.Set("a", 1, NoExpiration)
+	tc.Set("b", 2, time.Hour)
+	x, expiration, found := tc.GetWithExpiration("a")
+	if !found || x.(int) != 1 || !expiration.IsZero() {
+		t.Error("a is not 1 without expiration:", x, expiration)
+	}
+	x, expiration, found = tc.GetWithExpiration("b")
+	if !found || x.(int) != 2 || expiration.Before(time.Now()) {
+		t.Error("b is not 2 with a future expiration:", x, expiration)
+	}
+	_, _, found = tc.GetWithExpiration("c")
+	if found {
+		t.Error("c was found, but it was never set")
+	}
+}
+
+func TestShardedCacheExpiration(t *testing.T) {
+	tc := NewSharded[int](DefaultExpiration, 0, 4)
+	tc.Set("a", 1, time.Millisecond)
+	tc.Set("b", 2, NoExpiration)
+	<-time.After(5 * time.Millisecond)
+	if _, found := tc.Get("a"); found {
+		t.Error("Found a when it should have expired")
+	}
+	tc.DeleteExpired()
+	if n := tc.ItemCount(); n != 1 {
+		t.Errorf("Item count after DeleteExpired is not 1: %d", n)
+	}
+}
+
+func TestShardedCacheIncrement(t *testing.T) {
+	tc := NewSharded[int64](DefaultExpiration, 0, 4)
+	tc.Set("a", 1, DefaultExpiration)
+	n, err := tc.IncrementInt64("a", 2)
+	if err != nil {
+		t.Error("Error incrementing:", err)
+	}
+	if n != 3 {
+		t.Error("Returned number is not 3:", n)
+	}
+	n, err = tc.DecrementInt64("a", 1)
+	if err != nil {
+		t.Error("Error decrementing:", err)
+	}
+	if n != 2 {
+		t.Error("Returned number is not 2:", n)
+	}
+	if err = tc.Increment("a", 5); err != nil {
+		t.Error("Error incrementing:", err)
+	}
+	x, _ := tc.Get("a")
+	if x != 7 {
+		t.Error("a is not 7:", x)
+	}
+	if _, err = tc.IncrementInt64("b", 1); err == nil {
+		t.Error("Incremented b when it shouldn't exist")
+	}
+}
+
+func TestShardedCacheRange(t *testing.T) {
+	tc := NewSharded[int](DefaultExpiration, 0, 7)
+	for i, v := range shardedKeys {
+		tc.Set(v, i, DefaultExpiration)
+	}
+	seen := map[string]bool{}
+	tc.Range(func(k string, v int) bool {
+		if seen[k] {
+			t.Error(k, "was visited twice")
+		}
+		seen[k] = true
+		return true
+	})
+	if len(seen) != len(shardedKeys) {
+		t.Errorf("Range visited %d items instead of %d", len(seen), len(shardedKeys))
+	}
+	n := 0
+	tc.Range(func(k string, v int) bool {
+		n++
+		return n < 3
+	})
+	if n != 3 {
+		t.Error("Range did not stop after 3 items:", n)
+	}
+}
+
+func TestShardedCacheSerialization(t *testing.T) {
+	tc := NewSharded[string](DefaultExpiration, 0, 4)
+	for _, v := range shardedKeys {
+		tc.Set(v, v, DefaultExpiration)
+	}
+	fp := &bytes.Buffer{}
+	if err := tc.Save(fp); err != nil {
+		t.Fatal("Couldn't save cache to fp:", err)
+	}
+	buf := fp.Bytes()
+
+	oc := NewSharded[string](DefaultExpiration, 0, 3)
+	oc.Set("f", "not f", DefaultExpiration) // this should not be overwritten
+	if err := oc.Load(bytes.NewReader(buf)); err != nil {
+		t.Fatal("Couldn't load cache from fp:", err)
+	}
+	for _, v := range shardedKeys {
+		x, found := oc.Get(v)
+		if !found {
+			t.Error(v, "was not found")
+		}
+		if v == "f" {
+			if x != "not f" {
+				t.Error("f was overwritten")
+			}
+		} else if x != v {
+			t.Error(v, "is not", v, x)
+		}
+	}
+
+	// Sharded and unsharded caches use the same format.
+	c := New[string](DefaultExpiration, 0)
+	if err := c.Load(bytes.NewReader(buf)); err != nil {
+		t.Fatal("Couldn't load cache from fp:", err)
+	}
+	if n := c.ItemCount(); n != len(shardedKeys) {
+		t.Errorf("Item count is not %d: %d", len(shardedKeys), n)
+	}
+}
+
+func TestShardedCacheMaxItems(t *testing.T) {
+	tc := NewSharded[int](DefaultExpiration, 0, 4, WithMaxItems(8))
+	for i := 0; i < 100; i++ {
+		tc.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := tc.ItemCount(); n > 8 {
+		t.Errorf("Item count is more than 8: %d", n)
+	}
 }
 
 func BenchmarkShardedCacheGetExpiring(b *testing.B) {
@@ -43,7 +231,7 @@ func BenchmarkShardedCacheGetNotExpiring(b *testing.B) {
 
 func benchmarkShardedCacheGet(b *testing.B, exp time.Duration) {
 	b.StopTimer()
-	tc := unexportedNewSharded[string](exp, 0, 10)
+	tc := NewSharded[string](exp, 0, 10)
 	tc.Set("foobarba", "zquux", DefaultExpiration)
 	b.StartTimer()
 	for i := 0; i < b.N; i++ {
@@ -62,7 +250,7 @@ func BenchmarkShardedCacheGetManyConcurrentNotExpiring(b *testing.B) {
 func benchmarkShardedCacheGetManyConcurrent(b *testing.B, exp time.Duration) {
 	b.StopTimer()
 	n := 10000
-	tsc := unexportedNewSharded[string](exp, 0, 20)
+	tsc := NewSharded[string](exp, 0, 20)
 	keys := make([]string, n)
 	for i := 0; i < n; i++ {
 		k := "foo" + strconv.Itoa(i)
@@ -83,3 +271,63 @@ func benchmarkShardedCacheGetManyConcurrent(b *testing.B, exp time.Duration) {
 	b.StartTimer()
 	wg.Wait()
 }
+
+func BenchmarkShardedCacheSetExpiring(b *testing.B) {
+	benchmarkShardedCacheSet(b, 5*time.Minute)
+}
+
+func BenchmarkShardedCacheSetNotExpiring(b *testing.B) {
+	benchmarkShardedCacheSet(b, NoExpiration)
+}
+
+func benchmarkShardedCacheSet(b *testing.B, exp time.Duration) {
+	b.StopTimer()
+	tc := NewSharded[string](exp, 0, 10)
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		tc.Set("foo", "bar", DefaultExpiration)
+	}
+}
+
+func BenchmarkShardedCacheSetDelete(b *testing.B) {
+	b.StopTimer()
+	tc := NewSharded[string](DefaultExpiration, 0, 10)
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		tc.Set("foo", "bar", DefaultExpiration)
+		tc.Delete("foo")
+	}
+}
+
+func BenchmarkShardedCacheSetManyConcurrent(b *testing.B) {
+	b.StopTimer()
+	n := 10000
+	tsc := NewSharded[string](DefaultExpiration, 0, 20)
+	keys := make([]string, n)
+	for i := 0; i < n; i++ {
+		keys[i] = "foo" + strconv.Itoa(i)
+	}
+	each := b.N / n
+	wg := new(sync.WaitGroup)
+	wg.Add(n)
+	for _, v := range keys {
+		go func(k string) {
+			for j := 0; j < each; j++ {
+				tsc.Set(k, "bar", DefaultExpiration)
+			}
+			wg.Done()
+		}(v)
+	}
+	b.StartTimer()
+	wg.Wait()
+}
+
+func BenchmarkShardedCacheIncrementInt(b *testing.B) {
+	b.StopTimer()
+	tc := NewSharded[int](DefaultExpiration, 0, 10)
+	tc.Set("foo", 0, DefaultExpiration)
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = tc.IncrementInt("foo", 1)
+	}
+}
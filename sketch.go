@@ -0,0 +1,101 @@
+package cache
+
+// countMinSketch estimates how often hashes were seen, using four rows of
+// saturating four-bit counters. Once the number of increments reaches ten
+// times the number of distinct hashes it was sized for, all counters are
+// halved, so that the estimates favour recent use.
+type countMinSketch struct {
+	rows      [4][]uint8
+	mask      uint64
+	size      int
+	additions int
+	samples   int
+}
+
+var sketchSeeds = [4]uint64{
+	0xc3a5c85c97cb3127, 0xb492b66fbe98f273, 0x9ae16a3b2f90404f, 0xcbf29ce484222325,
+}
+
+// newCountMinSketch returns a sketch sized for about n distinct hashes. Each
+// row has four counters per hash, which keeps the overestimates caused by
+// collisions low.
+func newCountMinSketch(n int) *countMinSketch {
+	n = max(n, 16)
+	w := 64
+	for w < 4*n {
+		w *= 2
+	}
+	s := &countMinSketch{
+		mask:    uint64(w - 1),
+		size:    n,
+		samples: 10 * n,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, w)
+	}
+	return s
+}
+
+// grow resizes the sketch for about n distinct hashes without losing what it
+// has counted. Rows only ever double in width, and hashes keep the low bits
+// of their index, so each new counter starts with the count of the old one
+// its hashes used to share, which at worst overestimates, like a collision.
+func (s *countMinSketch) grow(n int) {
+	g := newCountMinSketch(n)
+	if g.mask <= s.mask {
+		return
+	}
+	for i := range g.rows {
+		for j := range g.rows[i] {
+			g.rows[i][j] = s.rows[i][uint64(j)&s.mask]
+		}
+	}
+	g.additions = s.additions
+	*s = *g
+}
+
+func (s *countMinSketch) index(i int, h uint64) uint64 {
+	h = (h ^ sketchSeeds[i]) * 0x9e3779b97f4a7c15
+	return (h >> 32) & s.mask
+}
+
+// increment counts one more occurrence of h.
+func (s *countMinSketch) increment(h uint64) {
+	for i := range s.rows {
+		if j := s.index(i, h); s.rows[i][j] < 15 {
+			s.rows[i][j]++
+		}
+	}
+	s.additions++
+	if s.additions >= s.samples {
+		s.age()
+	}
+}
+
+// estimate returns the estimated number of occurrences of h.
+func (s *countMinSketch) estimate(h uint64) uint8 {
+	min := uint8(15)
+	for i := range s.rows {
+		if c := s.rows[i][s.index(i, h)]; c < min {
+			min = c
+		}
+	}
+	return min
+}
+
+// age halves all counters.
+func (s *countMinSketch) age() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
+
+func (s *countMinSketch) clear() {
+	for i := range s.rows {
+		clear(s.rows[i])
+	}
+	s.additions = 0
+}
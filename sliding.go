@@ -0,0 +1,52 @@
+package cache
+
+import "time"
+
+// SetSliding Add an item to the cache, replacing any existing item, with a
+// sliding expiration: each time Get or GetWithExpiration returns the item, its
+// expiration time is pushed forward by d, so the item expires after it hasn't
+// been read for d. If the duration is 0 (DefaultExpiration), the cache's
+// default expiration time is used. If it is -1 (NoExpiration), the item never
+// expires.
+func (c *cache[K, T]) SetSliding(k K, x T, d time.Duration) {
+	var e int64
+	if d == DefaultExpiration {
+		d = c.defaultExpiration
+	}
+	if d > 0 {
+		e = c.clock.Now().Add(d).UnixNano()
+	} else {
+		d = 0
+	}
+	c.mu.Lock()
+	evictedItems := c.insert(k, &Item[T]{
+		Object:     x,
+		Expiration: e,
+		Sliding:    d,
+	})
+	c.mu.Unlock()
+	c.evicted(evictedItems)
+}
+
+// slide renews the sliding expiration of item, which was read from k, and
+// returns its new expiration time. The item is left alone if it has been
+// replaced or deleted in the meantime.
+func (c *cache[K, T]) slide(k K, item *Item[T]) int64 {
+	e := c.clock.Now().Add(item.Sliding).UnixNano()
+	c.mu.Lock()
+	if c.items[k] == item {
+		c.renew(k, item, e)
+	}
+	c.mu.Unlock()
+	return e
+}
+
+// renew replaces item, the item under k, by a copy that expires at e. The
+// item is replaced rather than changed in place, because Items and the map
+// passed to NewFrom share it with callers, who may read it without the lock.
+func (c *cache[K, T]) renew(k K, item *Item[T], e int64) {
+	renewed := *item
+	renewed.Expiration = e
+	c.items[k] = &renewed
+	c.expirations.set(k, e)
+}
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSetSliding(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	tc.SetSliding("a", 1, 50*time.Millisecond)
+	tc.Set("b", 2, 50*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		<-time.After(30 * time.Millisecond)
+		if _, found := tc.Get("a"); !found {
+			t.Fatal("a expired even though it was read within its sliding expiration")
+		}
+	}
+	if _, found := tc.Get("b"); found {
+		t.Error("b was found, but it doesn't have a sliding expiration")
+	}
+	<-time.After(60 * time.Millisecond)
+	if _, found := tc.Get("a"); found {
+		t.Error("a was found after it hasn't been read for its sliding expiration")
+	}
+	tc.DeleteExpired()
+	if n := tc.ItemCount(); n != 0 {
+		t.Errorf("Item count is not 0: %d", n)
+	}
+}
+
+func TestSetSlidingGetWithExpiration(t *testing.T) {
+	tc := New[int](time.Hour, 0)
+	tc.SetSliding("a", 1, DefaultExpiration)
+	before := tc.items["a"].Expiration
+	<-time.After(time.Millisecond)
+	_, expiration, found := tc.GetWithExpiration("a")
+	if !found {
+		t.Fatal("a was not found")
+	}
+	if expiration.UnixNano() <= before {
+		t.Error("expiration of a was not pushed forward")
+	}
+	if expiration.UnixNano() != tc.items["a"].Expiration {
+		t.Error("returned expiration is not the stored one")
+	}
+
+	tc.SetSliding("b", 2, NoExpiration)
+	if _, expiration, _ = tc.GetWithExpiration("b"); !expiration.IsZero() {
+		t.Error("b expires even though it was set with NoExpiration")
+	}
+}
+
+func TestWithSlidingExpiration(t *testing.T) {
+	tc := New[int](50*time.Millisecond, 0, WithSlidingExpiration())
+	tc.Set("a", 1, DefaultExpiration)
+	_ = tc.Add("b", 2, DefaultExpiration)
+	for i := 0; i < 3; i++ {
+		<-time.After(30 * time.Millisecond)
+		if _, found := tc.Get("a"); !found {
+			t.Fatal("a expired even though it was read within its sliding expiration")
+		}
+	}
+	if _, found := tc.Get("b"); found {
+		t.Error("b was found after it hasn't been read for its sliding expiration")
+	}
+}
+
+func TestSlidingSerialization(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	tc.SetSliding("a", 1, time.Hour)
+	fp := &bytes.Buffer{}
+	if err := tc.Save(fp); err != nil {
+		t.Fatal("Couldn't save cache to fp:", err)
+	}
+	oc := New[int](DefaultExpiration, 0)
+	if err := oc.Load(fp); err != nil {
+		t.Fatal("Couldn't load cache from fp:", err)
+	}
+	if d := oc.items["a"].Sliding; d != time.Hour {
+		t.Error("sliding expiration of a is not an hour:", d)
+	}
+}
+
+func TestSlidingItemsRace(t *testing.T) {
+	// Run with -race: renewing the expiration must not write to the items
+	// returned by Items.
+	tc := New[int](DefaultExpiration, 0)
+	tc.SetSliding("a", 1, time.Hour)
+	items := tc.Items()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			tc.Get("a")
+			tc.GetMulti([]string{"a"})
+		}
+	}()
+	var e int64
+	for i := 0; i < 100; i++ {
+		e += items["a"].Expiration
+	}
+	<-done
+	if e == 0 {
+		t.Error("a has no expiration time")
+	}
+}
@@ -0,0 +1,178 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of the counters of a cache, see Cache.Stats.
+type Stats struct {
+	// Hits is the number of reads that found an item.
+	Hits uint64
+	// Misses is the number of reads that didn't find an item.
+	Misses uint64
+	// Sets is the number of items stored, including overwrites.
+	Sets uint64
+	// Deletes is the number of items deleted manually.
+	Deletes uint64
+	// Expirations is the number of expired items deleted by DeleteExpired.
+	Expirations uint64
+	// Evictions is the number of items deleted to keep a bounded cache
+	// within its limit.
+	Evictions uint64
+	// Replacements is the number of items that were overwritten.
+	Replacements uint64
+	// Flushes is the number of items deleted by Flush.
+	Flushes uint64
+	// Invalidations is the number of items deleted by InvalidateTag.
+	Invalidations uint64
+	// LoadSuccesses and LoadFailures are the number of calls to GetOrLoad
+	// and RefreshPolicy loaders that returned without and with an error.
+	LoadSuccesses uint64
+	LoadFailures  uint64
+	// JanitorRuns is the number of times the janitor ran, and
+	// JanitorDuration the total time those runs took.
+	JanitorRuns     uint64
+	JanitorDuration time.Duration
+}
+
+// Evicted returns the number of items removed from the cache for the given
+// reason.
+func (s Stats) Evicted(reason EvictionReason) uint64 {
+	switch reason {
+	case Expired:
+		return s.Expirations
+	case Deleted:
+		return s.Deletes
+	case Replaced:
+		return s.Replacements
+	case Flushed:
+		return s.Flushes
+	case CapacityEvicted:
+		return s.Evictions
+	case Invalidated:
+		return s.Invalidations
+	}
+	return 0
+}
+
+// HitRatio returns the fraction of reads that found an item, or 0 if there
+// were no reads.
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+func (s *Stats) add(o Stats) {
+	s.Hits += o.Hits
+	s.Misses += o.Misses
+	s.Sets += o.Sets
+	s.Deletes += o.Deletes
+	s.Expirations += o.Expirations
+	s.Evictions += o.Evictions
+	s.Replacements += o.Replacements
+	s.Flushes += o.Flushes
+	s.Invalidations += o.Invalidations
+	s.LoadSuccesses += o.LoadSuccesses
+	s.LoadFailures += o.LoadFailures
+	s.JanitorRuns += o.JanitorRuns
+	s.JanitorDuration += o.JanitorDuration
+}
+
+// stats holds the counters of a cache. They are updated atomically, so that
+// reads can count hits and misses while only holding the read lock.
+type stats struct {
+	hits          atomic.Uint64
+	misses        atomic.Uint64
+	sets          atomic.Uint64
+	deletes       atomic.Uint64
+	expirations   atomic.Uint64
+	evictions     atomic.Uint64
+	replacements  atomic.Uint64
+	flushes       atomic.Uint64
+	invalidations atomic.Uint64
+	loadSuccesses atomic.Uint64
+	loadFailures  atomic.Uint64
+	janitorRuns   atomic.Uint64
+	janitorNanos  atomic.Int64
+}
+
+func (s *stats) snapshot() Stats {
+	return Stats{
+		Hits:            s.hits.Load(),
+		Misses:          s.misses.Load(),
+		Sets:            s.sets.Load(),
+		Deletes:         s.deletes.Load(),
+		Expirations:     s.expirations.Load(),
+		Evictions:       s.evictions.Load(),
+		Replacements:    s.replacements.Load(),
+		Flushes:         s.flushes.Load(),
+		Invalidations:   s.invalidations.Load(),
+		LoadSuccesses:   s.loadSuccesses.Load(),
+		LoadFailures:    s.loadFailures.Load(),
+		JanitorRuns:     s.janitorRuns.Load(),
+		JanitorDuration: time.Duration(s.janitorNanos.Load()),
+	}
+}
+
+func (s *stats) reset() {
+	s.hits.Store(0)
+	s.misses.Store(0)
+	s.sets.Store(0)
+	s.deletes.Store(0)
+	s.expirations.Store(0)
+	s.evictions.Store(0)
+	s.replacements.Store(0)
+	s.flushes.Store(0)
+	s.invalidations.Store(0)
+	s.loadSuccesses.Store(0)
+	s.loadFailures.Store(0)
+	s.janitorRuns.Store(0)
+	s.janitorNanos.Store(0)
+}
+
+// countLoad counts the result of a call to a loader.
+func (s *stats) countLoad(err error) {
+	if err != nil {
+		s.loadFailures.Add(1)
+	} else {
+		s.loadSuccesses.Add(1)
+	}
+}
+
+// Stats Returns a snapshot of the cache's hit, miss, store and deletion
+// counters. The counters are read one at a time while the cache may be in
+// use, so they aren't necessarily consistent with each other.
+func (c *cache[K, T]) Stats() Stats {
+	return c.stats.snapshot()
+}
+
+// ResetStats Sets all of the cache's counters to zero.
+func (c *cache[K, T]) ResetStats() {
+	c.stats.reset()
+}
+
+// countJanitorRun counts a run of the janitor that took d.
+func (s *stats) countJanitorRun(d time.Duration) {
+	s.janitorRuns.Add(1)
+	s.janitorNanos.Add(int64(d))
+}
+
+// Stats Returns the sum of the counters of all shards. See Cache.Stats.
+func (sc *shardedCache[T]) Stats() Stats {
+	s := sc.stats.snapshot()
+	for _, v := range sc.cs {
+		s.add(v.Stats())
+	}
+	return s
+}
+
+// ResetStats Sets the counters of all shards to zero.
+func (sc *shardedCache[T]) ResetStats() {
+	sc.stats.reset()
+	for _, v := range sc.cs {
+		v.ResetStats()
+	}
+}
@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tc := New[int](DefaultExpiration, 0, WithClock(clock), WithMaxItems(3))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("a", 2, DefaultExpiration)
+	_ = tc.Add("b", 3, time.Second)
+	_ = tc.Add("b", 4, DefaultExpiration)
+	_ = tc.Replace("c", 5, DefaultExpiration)
+	tc.Get("a")
+	tc.GetWithExpiration("b")
+	tc.Get("c")
+	tc.Delete("a")
+	tc.Delete("a")
+	clock.Advance(2 * time.Second)
+	tc.Get("b")
+	tc.DeleteExpired()
+	for _, k := range []string{"d", "e", "f", "g"} {
+		tc.Set(k, 0, DefaultExpiration)
+	}
+	_, _ = tc.GetOrLoad(context.Background(), "h", func(ctx context.Context) (int, time.Duration, error) {
+		return 0, DefaultExpiration, nil
+	})
+	_, _ = tc.GetOrLoad(context.Background(), "i", func(ctx context.Context) (int, time.Duration, error) {
+		return 0, DefaultExpiration, errors.New("database is down")
+	})
+	tc.Flush()
+	want := Stats{
+		Hits:          2,
+		Misses:        4,
+		Sets:          8,
+		Deletes:       1,
+		Expirations:   1,
+		Evictions:     2,
+		Replacements:  1,
+		Flushes:       3,
+		LoadSuccesses: 1,
+		LoadFailures:  1,
+	}
+	if s := tc.Stats(); s != want {
+		t.Errorf("stats are %+v, not %+v", s, want)
+	}
+	for r, n := range map[EvictionReason]uint64{Expired: 1, Deleted: 1, Replaced: 1, Flushed: 3, CapacityEvicted: 2} {
+		if got := tc.Stats().Evicted(r); got != n {
+			t.Errorf("%d items were evicted as %v, not %d", got, r, n)
+		}
+	}
+	if r := tc.Stats().HitRatio(); r != 2.0/6.0 {
+		t.Error("hit ratio is not 1/3:", r)
+	}
+	tc.ResetStats()
+	if s := tc.Stats(); s != (Stats{}) {
+		t.Errorf("stats are not zero after ResetStats: %+v", s)
+	}
+	if r := tc.Stats().HitRatio(); r != 0 {
+		t.Error("hit ratio without reads is not 0:", r)
+	}
+}
+
+func TestShardedCacheStats(t *testing.T) {
+	tc := NewSharded[int](DefaultExpiration, 0, 4)
+	for _, k := range shardedKeys {
+		tc.Set(k, 0, DefaultExpiration)
+		tc.Get(k)
+		tc.Get(k + "-missing")
+	}
+	s := tc.Stats()
+	n := uint64(len(shardedKeys))
+	if s.Sets != n || s.Hits != n || s.Misses != n {
+		t.Errorf("stats are not the sum of all shards: %+v", s)
+	}
+	tc.ResetStats()
+	if s = tc.Stats(); s != (Stats{}) {
+		t.Errorf("stats are not zero after ResetStats: %+v", s)
+	}
+}
+
+func BenchmarkCacheGetConcurrentStats(b *testing.B) {
+	tc := New[string](DefaultExpiration, 0)
+	tc.Set("foo", "bar", DefaultExpiration)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			tc.Get("foo")
+		}
+	})
+}
+
+func TestJanitorStats(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tc := New[int](DefaultExpiration, time.Second, WithClock(clock))
+	defer tc.Close()
+	clock.Advance(3 * time.Second)
+	if s := tc.Stats(); s.JanitorRuns != 3 {
+		t.Error("janitor runs are not 3:", s.JanitorRuns)
+	}
+	sc := NewSharded[int](DefaultExpiration, time.Second, 4, WithClock(clock))
+	defer sc.Close()
+	clock.Advance(2 * time.Second)
+	if s := sc.Stats(); s.JanitorRuns != 2 {
+		t.Error("sharded janitor runs are not 2:", s.JanitorRuns)
+	}
+}
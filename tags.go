@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"slices"
+	"time"
+)
+
+// SetWithTags Add an item to the cache, replacing any existing item, and tag
+// it with the given tags, so that it can be deleted together with the other
+// items carrying any of them using InvalidateTag. The duration is interpreted
+// as for Set. Storing another item under the same key, e.g. using Set,
+// replaces the tags too.
+func (c *cache[K, T]) SetWithTags(k K, x T, d time.Duration, tags ...string) {
+	var e int64
+	var sd time.Duration
+	if d == DefaultExpiration {
+		d = c.defaultExpiration
+	}
+	if d > 0 {
+		e = c.clock.Now().Add(d).UnixNano()
+		if c.sliding {
+			sd = d
+		}
+	}
+	c.mu.Lock()
+	evictedItems := c.insert(k, &Item[T]{
+		Object:     x,
+		Expiration: e,
+		Sliding:    sd,
+		Tags:       slices.Clone(tags),
+	})
+	c.mu.Unlock()
+	c.evicted(evictedItems)
+}
+
+// InvalidateTag Delete all items tagged with tag, including expired ones that
+// have not yet been cleaned up, and return how many there were. The items are
+// deleted at once, while holding the lock, and then passed to the eviction
+// functions with the reason Invalidated.
+func (c *cache[K, T]) InvalidateTag(tag string) int {
+	var evictedItems []evictedItem[K, T]
+	c.mu.Lock()
+	n := 0
+	observed := c.observesEvictions()
+	for k := range c.tags[tag] {
+		// Deleting k also removes it from the tag's keys, which Go allows
+		// while ranging over them.
+		v, found := c.delete(k)
+		if !found {
+			continue
+		}
+		n++
+		if observed {
+			evictedItems = append(evictedItems, evictedItem[K, T]{k, v, Invalidated})
+		}
+	}
+	c.mu.Unlock()
+	c.stats.invalidations.Add(uint64(n))
+	c.evicted(evictedItems)
+	return n
+}
+
+// tag adds k to the index of each of item's tags.
+func (c *cache[K, T]) tag(k K, item *Item[T]) {
+	for _, t := range item.Tags {
+		if c.tags == nil {
+			c.tags = map[string]map[K]struct{}{}
+		}
+		keys, found := c.tags[t]
+		if !found {
+			keys = map[K]struct{}{}
+			c.tags[t] = keys
+		}
+		keys[k] = struct{}{}
+	}
+}
+
+// untag removes k, whose item was item, from the index of each of its tags.
+func (c *cache[K, T]) untag(k K, item *Item[T]) {
+	for _, t := range item.Tags {
+		if keys, found := c.tags[t]; found {
+			delete(keys, k)
+			if len(keys) == 0 {
+				delete(c.tags, t)
+			}
+		}
+	}
+}
+
+// SetWithTags Add an item to the right shard and tag it. See
+// Cache.SetWithTags.
+func (sc *shardedCache[T]) SetWithTags(k string, x T, d time.Duration, tags ...string) {
+	sc.bucket(k).SetWithTags(k, x, d, tags...)
+}
+
+// InvalidateTag Delete all items tagged with tag from all shards, and return
+// how many there were. Each shard is invalidated atomically, but not all of
+// them at once.
+func (sc *shardedCache[T]) InvalidateTag(tag string) int {
+	n := 0
+	for _, v := range sc.cs {
+		n += v.InvalidateTag(tag)
+	}
+	return n
+}
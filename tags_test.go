@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestInvalidateTag(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	var evicted []string
+	tc.OnEvictedWithReason(func(k string, item Item[int], reason EvictionReason) {
+		if reason != Invalidated {
+			t.Error("reason for", k, "is", reason)
+		}
+		// The cache must not be locked.
+		tc.Get(k)
+		evicted = append(evicted, k)
+	})
+	tc.SetWithTags("a", 1, DefaultExpiration, "user:1", "posts")
+	tc.SetWithTags("b", 2, DefaultExpiration, "user:1")
+	tc.SetWithTags("c", 3, DefaultExpiration, "user:2", "posts")
+	tc.Set("d", 4, DefaultExpiration)
+	if n := tc.InvalidateTag("user:1"); n != 2 {
+		t.Error("InvalidateTag deleted", n, "items")
+	}
+	sort.Strings(evicted)
+	if !slices.Equal(evicted, []string{"a", "b"}) {
+		t.Error("OnEvictedWithReason was called for", evicted)
+	}
+	if n := tc.ItemCount(); n != 2 {
+		t.Error("ItemCount is not 2:", n)
+	}
+	if n := tc.InvalidateTag("user:1"); n != 0 {
+		t.Error("InvalidateTag deleted", n, "items again")
+	}
+	if n := tc.InvalidateTag("posts"); n != 1 {
+		t.Error("InvalidateTag deleted", n, "items")
+	}
+	if _, found := tc.Get("d"); !found {
+		t.Error("untagged item was deleted")
+	}
+	s := tc.Stats()
+	if s.Invalidations != 3 || s.Evicted(Invalidated) != 3 {
+		t.Error("Stats has", s.Invalidations, "invalidations")
+	}
+	if len(tc.tags) != 0 {
+		t.Error("tag index was not emptied:", tc.tags)
+	}
+}
+
+func TestTagsReplaced(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	tc.SetWithTags("a", 1, DefaultExpiration, "x")
+	tc.SetWithTags("a", 2, DefaultExpiration, "y")
+	if n := tc.InvalidateTag("x"); n != 0 {
+		t.Error("InvalidateTag deleted", n, "items with a replaced tag")
+	}
+	tc.Set("a", 3, DefaultExpiration)
+	if n := tc.InvalidateTag("y"); n != 0 {
+		t.Error("InvalidateTag deleted", n, "items overwritten by Set")
+	}
+	tc.SetWithTags("b", 1, DefaultExpiration, "z")
+	tc.Update("b", func(x int, found bool) (int, bool) {
+		return x + 1, true
+	})
+	if _, err := Add(tc, "b", 1); err != nil {
+		t.Error(err)
+	}
+	if n := tc.InvalidateTag("z"); n != 1 {
+		t.Error("Update or Add dropped the tags of b")
+	}
+}
+
+func TestTagsRemoved(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tc := New[int](DefaultExpiration, 0, WithClock(clock))
+	tc.SetWithTags("a", 1, DefaultExpiration, "x")
+	tc.SetWithTags("b", 2, time.Second, "x")
+	tc.SetWithTags("c", 3, DefaultExpiration, "y")
+	tc.Delete("a")
+	clock.Advance(2 * time.Second)
+	tc.DeleteExpired()
+	if n := tc.InvalidateTag("x"); n != 0 {
+		t.Error("InvalidateTag deleted", n, "deleted or expired items")
+	}
+	tc.Flush()
+	if n := tc.InvalidateTag("y"); n != 0 {
+		t.Error("InvalidateTag deleted", n, "flushed items")
+	}
+	tc.SetWithTags("d", 4, DefaultExpiration, "y")
+	if n := tc.InvalidateTag("y"); n != 1 {
+		t.Error("InvalidateTag deleted", n, "items after Flush")
+	}
+}
+
+func TestTagsNewFrom(t *testing.T) {
+	tc := NewFrom(DefaultExpiration, 0, map[string]*Item[int]{
+		"a": {Object: 1, Tags: []string{"x"}},
+		"b": {Object: 2},
+	})
+	if n := tc.InvalidateTag("x"); n != 1 {
+		t.Error("InvalidateTag deleted", n, "items")
+	}
+}
+
+func TestShardedInvalidateTag(t *testing.T) {
+	tc := NewSharded[int](DefaultExpiration, 0, 4)
+	for i := 0; i < 100; i++ {
+		tc.SetWithTags(fmt.Sprint(i), i, DefaultExpiration, fmt.Sprint("mod", i%3))
+	}
+	if n := tc.InvalidateTag("mod0"); n != 34 {
+		t.Error("InvalidateTag deleted", n, "items")
+	}
+	if n := tc.ItemCount(); n != 66 {
+		t.Error("ItemCount is not 66:", n)
+	}
+	if s := tc.Stats(); s.Invalidations != 34 {
+		t.Error("Stats has", s.Invalidations, "invalidations")
+	}
+}
+
+func TestSetWithTagsReusedSlice(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	tc.OnEvicted(func(k string, x int) {})
+	tags := []string{"a"}
+	tc.SetWithTags("k", 1, DefaultExpiration, tags...)
+	tags[0] = "b"
+	tc.Set("k", 2, DefaultExpiration)
+	if n := tc.InvalidateTag("a"); n != 0 {
+		t.Error("InvalidateTag deleted", n, "items stored using Set")
+	}
+	if n := tc.InvalidateTag("b"); n != 0 {
+		t.Error("InvalidateTag deleted", n, "items never tagged b")
+	}
+	if x, found := tc.Get("k"); !found || x != 2 {
+		t.Error("k is", x, found)
+	}
+}
@@ -0,0 +1,175 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Segments of the tinyLFU policy.
+const (
+	windowSegment = iota
+	probationSegment
+	protectedSegment
+)
+
+type tinyLFUEntry[K comparable] struct {
+	key     K
+	hash    uint64
+	segment int
+}
+
+// tinyLFU is the TinyLFU policy. New keys enter the window, an LRU list of
+// about 1% of the keys. Keys pushed out of the window join the probation
+// segment of the main LRU, and move on to the protected segment (of at most
+// 80% of the main keys) when they are read again. To evict a key, the key
+// that most recently joined the probation segment competes with the least
+// recently used key in it, and the one with the lowest estimated frequency
+// is evicted.
+type tinyLFU[K comparable] struct {
+	mu        sync.Mutex
+	hasher    *keyHasher[K]
+	sketch    *countMinSketch
+	window    *list.List
+	probation *list.List
+	protected *list.List
+	elems     map[K]*list.Element
+}
+
+func newTinyLFU[K comparable](n int) *tinyLFU[K] {
+	return &tinyLFU[K]{
+		hasher:    newKeyHasher[K](),
+		sketch:    newCountMinSketch(n),
+		window:    list.New(),
+		probation: list.New(),
+		protected: list.New(),
+		elems:     map[K]*list.Element{},
+	}
+}
+
+func (p *tinyLFU[K]) list(segment int) *list.List {
+	switch segment {
+	case windowSegment:
+		return p.window
+	case probationSegment:
+		return p.probation
+	}
+	return p.protected
+}
+
+// move moves e to the front of the given segment.
+func (p *tinyLFU[K]) move(e *list.Element, segment int) *list.Element {
+	entry := e.Value.(*tinyLFUEntry[K])
+	p.list(entry.segment).Remove(e)
+	entry.segment = segment
+	e = p.list(segment).PushFront(entry)
+	p.elems[entry.key] = e
+	return e
+}
+
+// windowSize and protectedSize return the maximum number of keys in the
+// window and protected segments.
+func (p *tinyLFU[K]) windowSize() int {
+	return max(1, len(p.elems)/100)
+}
+
+func (p *tinyLFU[K]) protectedSize() int {
+	return max(1, (len(p.elems)-p.windowSize())*8/10)
+}
+
+// add records that k was stored.
+func (p *tinyLFU[K]) add(k K) {
+	p.mu.Lock()
+	if e, found := p.elems[k]; found {
+		p.hit(e)
+		p.mu.Unlock()
+		return
+	}
+	entry := &tinyLFUEntry[K]{
+		key:     k,
+		hash:    p.hasher.hash(k),
+		segment: windowSegment,
+	}
+	p.elems[k] = p.window.PushFront(entry)
+	if len(p.elems) > 2*p.sketch.size {
+		p.sketch.grow(2 * len(p.elems))
+	}
+	p.sketch.increment(entry.hash)
+	for p.window.Len() > p.windowSize() {
+		p.move(p.window.Back(), probationSegment)
+	}
+	p.mu.Unlock()
+}
+
+// access records that k was read, if it is tracked.
+func (p *tinyLFU[K]) access(k K) {
+	p.mu.Lock()
+	if e, found := p.elems[k]; found {
+		p.hit(e)
+	}
+	p.mu.Unlock()
+}
+
+// hit counts a use of e and promotes it.
+func (p *tinyLFU[K]) hit(e *list.Element) {
+	entry := e.Value.(*tinyLFUEntry[K])
+	p.sketch.increment(entry.hash)
+	switch entry.segment {
+	case windowSegment:
+		p.window.MoveToFront(e)
+	case probationSegment:
+		p.move(e, protectedSegment)
+		for p.protected.Len() > p.protectedSize() {
+			p.move(p.protected.Back(), probationSegment)
+		}
+	case protectedSegment:
+		p.protected.MoveToFront(e)
+	}
+}
+
+// remove stops tracking k.
+func (p *tinyLFU[K]) remove(k K) {
+	p.mu.Lock()
+	if e, found := p.elems[k]; found {
+		p.list(e.Value.(*tinyLFUEntry[K]).segment).Remove(e)
+		delete(p.elems, k)
+	}
+	p.mu.Unlock()
+}
+
+// evict stops tracking the key that should be evicted next and returns it.
+func (p *tinyLFU[K]) evict() (K, bool) {
+	p.mu.Lock()
+	if p.probation.Len() == 0 && p.protected.Len() > 0 {
+		p.move(p.protected.Back(), probationSegment)
+	}
+	victim := p.probation.Back()
+	if victim == nil {
+		victim = p.window.Back()
+	} else if candidate := p.probation.Front(); candidate != victim {
+		c := candidate.Value.(*tinyLFUEntry[K])
+		v := victim.Value.(*tinyLFUEntry[K])
+		if p.sketch.estimate(c.hash) <= p.sketch.estimate(v.hash) {
+			victim = candidate
+		}
+	}
+	if victim == nil {
+		p.mu.Unlock()
+		var zero K
+		return zero, false
+	}
+	entry := victim.Value.(*tinyLFUEntry[K])
+	p.list(entry.segment).Remove(victim)
+	delete(p.elems, entry.key)
+	p.mu.Unlock()
+	return entry.key, true
+}
+
+func (p *tinyLFU[K]) clear() {
+	p.mu.Lock()
+	p.window.Init()
+	p.probation.Init()
+	p.protected.Init()
+	p.elems = map[K]*list.Element{}
+	p.sketch.clear()
+	p.mu.Unlock()
+}
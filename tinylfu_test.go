@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestTinyLFUKeepsFrequentItems(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0, WithMaxItems(100), WithEvictionPolicy(TinyLFU))
+	for i := 0; i < 100; i++ {
+		tc.Set("hot"+strconv.Itoa(i), i, DefaultExpiration)
+	}
+	for j := 0; j < 10; j++ {
+		for i := 0; i < 100; i++ {
+			tc.Get("hot" + strconv.Itoa(i))
+		}
+	}
+	// A scan of items that are used once must not flush the hot items.
+	for i := 0; i < 1000; i++ {
+		tc.Set("scan"+strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := tc.ItemCount(); n != 100 {
+		t.Error("item count is not 100:", n)
+	}
+	hot := 0
+	for i := 0; i < 100; i++ {
+		if _, found := tc.Get("hot" + strconv.Itoa(i)); found {
+			hot++
+		}
+	}
+	if hot < 80 {
+		t.Error("only", hot, "of 100 hot items survived a scan")
+	}
+}
+
+func TestTinyLFUEvictions(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0, WithMaxItems(10), WithEvictionPolicy(TinyLFU))
+	evicted := 0
+	tc.OnEvicted(func(string, int) {
+		evicted++
+	})
+	for i := 0; i < 50; i++ {
+		tc.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := tc.ItemCount(); n != 10 {
+		t.Error("item count is not 10:", n)
+	}
+	if evicted != 40 {
+		t.Error("evictions are not 40:", evicted)
+	}
+	tc.Delete("49")
+	tc.Flush()
+	tc.Set("a", 1, DefaultExpiration)
+	if x, found := tc.Get("a"); !found || x != 1 {
+		t.Error("a was not stored after Flush")
+	}
+}
+
+func TestTinyLFUMaxCost(t *testing.T) {
+	tc := New[blob](DefaultExpiration, 0, WithMaxCost(100), WithEvictionPolicy(TinyLFU))
+	for i := 0; i < 100; i++ {
+		tc.Set(strconv.Itoa(i), make(blob, 10), DefaultExpiration)
+	}
+	if n := tc.TotalCost(); n > 100 {
+		t.Error("total cost is over budget:", n)
+	}
+}
+
+func TestCountMinSketch(t *testing.T) {
+	s := newCountMinSketch(64)
+	for i := 0; i < 5; i++ {
+		s.increment(1)
+	}
+	s.increment(2)
+	if e := s.estimate(1); e != 5 {
+		t.Error("estimate of 1 is not 5:", e)
+	}
+	if e := s.estimate(2); e != 1 {
+		t.Error("estimate of 2 is not 1:", e)
+	}
+	for i := 0; i < 20; i++ {
+		s.increment(3)
+	}
+	if e := s.estimate(3); e != 15 {
+		t.Error("estimate of 3 does not saturate at 15:", e)
+	}
+	s.age()
+	if e := s.estimate(1); e != 2 {
+		t.Error("estimate of 1 is not halved:", e)
+	}
+}
+
+func TestCountMinSketchGrow(t *testing.T) {
+	s := newCountMinSketch(16)
+	for h := uint64(0); h < 16; h++ {
+		for i := uint64(0); i < h%8; i++ {
+			s.increment(h)
+		}
+	}
+	s.grow(1000)
+	if s.size != 1000 {
+		t.Error("sketch was not grown:", s.size)
+	}
+	for h := uint64(0); h < 16; h++ {
+		if e := s.estimate(h); e < uint8(h%8) {
+			t.Error("estimate of", h, "is", e, "after growing")
+		}
+	}
+}
+
+// zipfTrace returns n keys drawn from a Zipf distribution over m keys.
+func zipfTrace(n int, m uint64, s float64) []string {
+	z := rand.NewZipf(rand.New(rand.NewSource(1)), s, 1, m-1)
+	trace := make([]string, n)
+	for i := range trace {
+		trace[i] = strconv.FormatUint(z.Uint64(), 10)
+	}
+	return trace
+}
+
+// scanTrace interleaves trace with scans of keys that are used only once.
+func scanTrace(trace []string, every, length int) []string {
+	var out []string
+	scanned := 0
+	for i, k := range trace {
+		out = append(out, k)
+		if i%every == every-1 {
+			for j := 0; j < length; j++ {
+				out = append(out, "scan"+strconv.Itoa(scanned))
+				scanned++
+			}
+		}
+	}
+	return out
+}
+
+func benchmarkHitRatio(b *testing.B, p EvictionPolicy, trace []string) {
+	var hits, reads int
+	for i := 0; i < b.N; i++ {
+		tc := New[struct{}](DefaultExpiration, 0, WithMaxItems(1000), WithEvictionPolicy(p))
+		for _, k := range trace {
+			reads++
+			if _, found := tc.Get(k); found {
+				hits++
+			} else {
+				tc.Set(k, struct{}{}, DefaultExpiration)
+			}
+		}
+	}
+	b.ReportMetric(float64(hits)/float64(reads), "hit-ratio")
+}
+
+func BenchmarkHitRatioZipfLRU(b *testing.B) {
+	benchmarkHitRatio(b, LRU, zipfTrace(100000, 100000, 1.01))
+}
+
+func BenchmarkHitRatioZipfTinyLFU(b *testing.B) {
+	benchmarkHitRatio(b, TinyLFU, zipfTrace(100000, 100000, 1.01))
+}
+
+func BenchmarkHitRatioZipfScanLRU(b *testing.B) {
+	benchmarkHitRatio(b, LRU, scanTrace(zipfTrace(100000, 100000, 1.01), 1000, 2000))
+}
+
+func BenchmarkHitRatioZipfScanTinyLFU(b *testing.B) {
+	benchmarkHitRatio(b, TinyLFU, scanTrace(zipfTrace(100000, 100000, 1.01), 1000, 2000))
+}
+
+func BenchmarkTinyLFUSetGet(b *testing.B) {
+	tc := New[int](DefaultExpiration, 0, WithMaxItems(1000), WithEvictionPolicy(TinyLFU))
+	trace := zipfTrace(1<<16, 10000, 1.01)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k := trace[i&(1<<16-1)]
+		if _, found := tc.Get(k); !found {
+			tc.Set(k, i, DefaultExpiration)
+		}
+	}
+}
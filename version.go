@@ -0,0 +1,91 @@
+package cache
+
+import "time"
+
+// nextVersion returns a version that no item of the cache has had before.
+// It must be called while holding c.mu.
+func (c *cache[K, T]) nextVersion() uint64 {
+	c.version++
+	return c.version
+}
+
+// GetWithVersion Get an item from the cache together with its version, which
+// changes whenever the item is stored or its value is changed (e.g. by Set,
+// Compute or Increment), but not when it is only read or its sliding
+// expiration is renewed. Pass the version to CompareAndSwap or
+// CompareAndDelete to change the item only if nobody else has changed it in
+// the meantime. Otherwise GetWithVersion behaves like Get.
+func (c *cache[K, T]) GetWithVersion(k K) (T, uint64, bool) {
+	c.mu.RLock()
+	item, found := c.items[k]
+	if !found {
+		c.mu.RUnlock()
+		c.stats.misses.Add(1)
+		var zero T
+		return zero, 0, false
+	}
+	var rp *RefreshPolicy[K, T]
+	if item.Expiration > 0 {
+		now := c.now()
+		if now > item.Expiration && !c.refresh.serveStale(item, now) {
+			c.mu.RUnlock()
+			c.stats.misses.Add(1)
+			var zero T
+			return zero, 0, false
+		}
+		if c.refresh.due(item, now) {
+			rp = c.refresh
+		}
+	}
+	if c.policy != nil {
+		c.policy.access(k)
+	}
+	x, v := item.Object, item.Version
+	sliding := item.Sliding > 0
+	c.mu.RUnlock()
+	c.stats.hits.Add(1)
+	if rp != nil {
+		c.startRefresh(k, rp)
+	}
+	if sliding {
+		c.slide(k, item)
+	}
+	return x, v, true
+}
+
+// CompareAndSwap Set a new value for the cache key only if the item exists,
+// hasn't expired, and still has the given version, as returned by
+// GetWithVersion. The duration is interpreted as for Set. It reports whether
+// the value was stored, which it never is if the cache is closed.
+func (c *cache[K, T]) CompareAndSwap(k K, version uint64, x T, d time.Duration) bool {
+	c.mu.Lock()
+	item, found := c.items[k]
+	if c.closed || !found || item.Version != version || c.expired(item) {
+		c.mu.Unlock()
+		return false
+	}
+	evictedItems := c.set(k, x, d)
+	c.mu.Unlock()
+	c.evicted(evictedItems)
+	return true
+}
+
+// CompareAndDelete Delete an item from the cache only if it exists, hasn't
+// expired, and still has the given version, as returned by GetWithVersion.
+// It reports whether the item was deleted.
+func (c *cache[K, T]) CompareAndDelete(k K, version uint64) bool {
+	c.mu.Lock()
+	item, found := c.items[k]
+	if !found || item.Version != version || c.expired(item) {
+		c.mu.Unlock()
+		return false
+	}
+	c.delete(k)
+	observed := c.observesEvictions()
+	c.mu.Unlock()
+	c.stats.deletes.Add(1)
+	if observed {
+		c.evicted([]evictedItem[K, T]{{k, item, Deleted}})
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCompareAndSwap(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	if _, _, found := tc.GetWithVersion("a"); found {
+		t.Error("missing item was found")
+	}
+	tc.Set("a", 1, DefaultExpiration)
+	x, v1, found := tc.GetWithVersion("a")
+	if !found || x != 1 || v1 == 0 {
+		t.Error("GetWithVersion returned", x, v1, found)
+	}
+	if !tc.CompareAndSwap("a", v1, 2, DefaultExpiration) {
+		t.Error("CompareAndSwap failed for the current version")
+	}
+	x, v2, _ := tc.GetWithVersion("a")
+	if x != 2 || v2 <= v1 {
+		t.Error("CompareAndSwap did not store a new version:", x, v1, v2)
+	}
+	if tc.CompareAndSwap("a", v1, 3, DefaultExpiration) {
+		t.Error("CompareAndSwap succeeded for an old version")
+	}
+	if tc.CompareAndSwap("b", 0, 3, DefaultExpiration) {
+		t.Error("CompareAndSwap stored a missing item")
+	}
+	if x, _ := tc.Get("a"); x != 2 {
+		t.Error("a is not 2:", x)
+	}
+}
+
+func TestCompareAndDelete(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	var reason EvictionReason
+	tc.OnEvictedWithReason(func(k string, item Item[int], r EvictionReason) {
+		reason = r
+	})
+	tc.Set("a", 1, DefaultExpiration)
+	_, v, _ := tc.GetWithVersion("a")
+	tc.Set("a", 1, DefaultExpiration)
+	if tc.CompareAndDelete("a", v) {
+		t.Error("CompareAndDelete deleted a replaced item")
+	}
+	_, v, _ = tc.GetWithVersion("a")
+	if !tc.CompareAndDelete("a", v) {
+		t.Error("CompareAndDelete failed for the current version")
+	}
+	if _, found := tc.Get("a"); found {
+		t.Error("a was not deleted")
+	}
+	if reason != Deleted {
+		t.Error("reason is not Deleted:", reason)
+	}
+}
+
+func TestVersionChanges(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tc := New[int](DefaultExpiration, 0, WithClock(clock))
+	tc.SetSliding("a", 1, time.Minute)
+	_, v1, _ := tc.GetWithVersion("a")
+	clock.Advance(time.Second)
+	tc.Get("a")
+	if _, v2, _ := tc.GetWithVersion("a"); v2 != v1 {
+		t.Error("reading a changed its version:", v1, v2)
+	}
+	tc.IncrementInt("a", 1)
+	_, v3, _ := tc.GetWithVersion("a")
+	if v3 == v1 {
+		t.Error("IncrementInt did not change the version")
+	}
+	tc.Update("a", func(old int, found bool) (int, bool) {
+		return old + 1, true
+	})
+	if _, v4, _ := tc.GetWithVersion("a"); v4 == v3 {
+		t.Error("Update did not change the version")
+	}
+}
+
+func TestCompareAndSwapExpiredOrClosed(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tc := New[int](DefaultExpiration, 0, WithClock(clock))
+	tc.Set("a", 1, time.Second)
+	_, v, _ := tc.GetWithVersion("a")
+	clock.Advance(2 * time.Second)
+	if tc.CompareAndSwap("a", v, 2, DefaultExpiration) {
+		t.Error("CompareAndSwap replaced an expired item")
+	}
+	if tc.CompareAndDelete("a", v) {
+		t.Error("CompareAndDelete deleted an expired item")
+	}
+	tc.Set("b", 1, DefaultExpiration)
+	_, v, _ = tc.GetWithVersion("b")
+	tc.Close()
+	if tc.CompareAndSwap("b", v, 2, DefaultExpiration) {
+		t.Error("closed cache stored b")
+	}
+}
+
+func TestCompareAndSwapConcurrent(t *testing.T) {
+	tc := New[int](DefaultExpiration, 0)
+	tc.Set("n", 0, DefaultExpiration)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				for {
+					x, v, _ := tc.GetWithVersion("n")
+					if tc.CompareAndSwap("n", v, x+1, DefaultExpiration) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if x, _ := tc.Get("n"); x != 1000 {
+		t.Error("n is not 1000:", x)
+	}
+}
+
+func TestShardedCompareAndSwap(t *testing.T) {
+	tc := NewSharded[int](DefaultExpiration, 0, 4)
+	for _, k := range shardedKeys {
+		tc.Set(k, 1, DefaultExpiration)
+		x, v, _ := tc.GetWithVersion(k)
+		if !tc.CompareAndSwap(k, v, x+1, DefaultExpiration) {
+			t.Error("CompareAndSwap failed for", k)
+		}
+	}
+	for _, k := range shardedKeys {
+		x, v, _ := tc.GetWithVersion(k)
+		if x != 2 {
+			t.Error(k, "is not 2:", x)
+		}
+		if !tc.CompareAndDelete(k, v) {
+			t.Error("CompareAndDelete failed for", k)
+		}
+	}
+	if n := tc.ItemCount(); n != 0 {
+		t.Error("items were not deleted:", n)
+	}
+}